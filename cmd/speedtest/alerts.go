@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"time"
+)
+
+type alertsCfg struct {
+	enabled   bool
+	cfg       alert.Cfg
+	notifiers []*hocon.Config
+}
+
+func parseAlertsCfg(config *hocon.Config) (*alertsCfg, error) {
+	aCfg := config.GetConfig("alerts")
+	if aCfg == nil || !aCfg.GetBoolean("enabled") {
+		return &alertsCfg{enabled: false}, nil
+	}
+
+	repeat := time.Duration(0)
+	if aCfg.Get("repeat-interval") != nil {
+		d, err := parseDuration(aCfg, "repeat-interval")
+		if err != nil {
+			return nil, err
+		}
+		repeat = d
+	}
+
+	rules := make([]alert.Rule, 0)
+	for _, r := range aCfg.GetArray("rules") {
+		obj, ok := r.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("alert rule must be an object, actual: %v", r)
+		}
+		rule, err := parseRule(obj.ToConfig())
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("at least one alert rule is required")
+	}
+
+	notifiers := make([]*hocon.Config, 0)
+	for _, n := range aCfg.GetArray("notifiers") {
+		obj, ok := n.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("alert notifier must be an object, actual: %v", n)
+		}
+		notifiers = append(notifiers, obj.ToConfig())
+	}
+
+	return &alertsCfg{
+		enabled:   true,
+		cfg:       alert.Cfg{Rules: rules, RepeatInterval: repeat},
+		notifiers: notifiers,
+	}, nil
+}
+
+func parseRule(cfg *hocon.Config) (alert.Rule, error) {
+	name := cfg.GetString("name")
+	if name == "" {
+		return nil, fmt.Errorf("alert rule must have a name: %v", cfg)
+	}
+	metric := alert.Metric(cfg.GetString("metric"))
+	switch metric {
+	case alert.Download, alert.Upload, alert.Ping:
+	default:
+		return nil, fmt.Errorf("unsupported metric: %s of alert rule: %s", metric, name)
+	}
+	condition := alert.Condition(cfg.GetString("condition"))
+	switch condition {
+	case alert.Below, alert.Above:
+	default:
+		return nil, fmt.Errorf("unsupported condition: %s of alert rule: %s", condition, name)
+	}
+
+	ruleType := cfg.GetString("type")
+	switch ruleType {
+	case "CONSECUTIVE":
+		count := cfg.GetInt("count")
+		if count < 1 {
+			return nil, fmt.Errorf("count of alert rule: %s must be positive", name)
+		}
+		return alert.Consecutive{
+			RuleName:  name,
+			Metric:    metric,
+			Condition: condition,
+			Threshold: parseFloat(cfg, "threshold"),
+			Count:     count,
+		}, nil
+	case "PERCENTILE":
+		window, err := parseDuration(cfg, "window")
+		if err != nil {
+			return nil, err
+		}
+		p := parseFloat(cfg, "percentile")
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("percentile of alert rule: %s must be in range (0, 100]", name)
+		}
+		return alert.Percentile{
+			RuleName:   name,
+			Metric:     metric,
+			Condition:  condition,
+			Threshold:  parseFloat(cfg, "threshold"),
+			Percentile: p,
+			Window:     window,
+			MinSamples: cfg.GetInt("min-samples"),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported alert rule type: %s", ruleType)
+}
+
+func createNotifiers(config *hocon.Config, cfgs []*hocon.Config, logger *logging.Logger) ([]alert.Notifier, error) {
+	notifiers := make([]alert.Notifier, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		n, err := createNotifier(config, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+// createNotifier creates notifier configured with cfg. EMAIL notifier uses email block of config
+func createNotifier(config, cfg *hocon.Config, logger *logging.Logger) (alert.Notifier, error) {
+	notifierType := cfg.GetString("type")
+	switch notifierType {
+	case "LOG":
+		return alert.Log{Logger: logger}, nil
+	case "WEBHOOK":
+		return createWebhook(cfg.GetConfig("webhook"), logger)
+	case "EMAIL":
+		return createEmailNotifier(config, cfg)
+	}
+
+	return nil, fmt.Errorf("unsupported alert notifier type: %s", notifierType)
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = ""
+
+const usage = `Usage: speedtest [command] [flags]
+
+Commands:
+  run              runs speed tests on schedule, the default when no command is given
+  once             runs a single speed test and prints its results
+  report           prints statistics and SLA of results from the storage
+  serve            serves endpoints of LAN measurements
+  config validate  checks the config
+  config print     prints the config with overrides applied and secrets redacted
+  version          prints version of the binary
+  help             prints this help
+
+Commands reading the config accept:
+  -config path     HOCON config, reference.conf by default
+  -set key=value   overrides value of the config, e.g. -set storage.type=IN-MEMORY. Can be repeated
+
+Run 'speedtest <command> -h' to see all flags of the command.
+`
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func printUsage(w io.Writer) {
+	_, _ = io.WriteString(w, usage)
+}
+
+// configSource are flags of the commands reading the config
+type configSource struct {
+	path      string
+	overrides overrides
+}
+
+func addConfigFlags(flags *flag.FlagSet) *configSource {
+	s := &configSource{}
+	flags.StringVar(&s.path, "config", "reference.conf", "path of HOCON config")
+	flags.Var(&s.overrides, "set", "overrides value of the config as key=value, e.g. storage.type=IN-MEMORY. Can be repeated")
+	return s
+}
+
+// load parses the config and applies overrides in order of the flags
+func (s *configSource) load() (*hocon.Config, error) {
+	cfg, err := hocon.ParseResource(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config: %s: %w", s.path, err)
+	}
+	for _, o := range s.overrides {
+		override, err := hocon.ParseString(o)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override: %s: %w", o, err)
+		}
+		cfg = override.WithFallback(cfg)
+	}
+	return cfg, nil
+}
+
+// mustLoad loads the config and exits the process when it could not be loaded
+func (s *configSource) mustLoad() *hocon.Config {
+	cfg, err := s.load()
+	if err != nil {
+		logging.Default().Fatal("could not load config", "err", err)
+	}
+	return cfg
+}
+
+// overrides are repeated -set flags. Every override is HOCON assignment, so numbers, durations, objects and arrays are
+// parsed like in the file. Other values are quoted, when they contain characters not allowed in unquoted strings
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ", ")
+}
+
+func (o *overrides) Set(s string) error {
+	key, value := s, ""
+	if i := strings.Index(s, "="); i >= 0 {
+		key, value = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	}
+	if key == "" || key == s {
+		return fmt.Errorf("override must be key=value, actual: %s", s)
+	}
+	*o = append(*o, key+" = "+hoconValue(value))
+	return nil
+}
+
+// hoconValue quotes the value, unless it is valid unquoted HOCON value, e.g. 10s, or it is already quoted,
+// an object, an array or a substitution
+func hoconValue(v string) string {
+	if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "{") || strings.HasPrefix(v, "[") || strings.HasPrefix(v, "${") {
+		return v
+	}
+	if v == "" || strings.ContainsAny(v, "$\"{}[]:=,+#`^?!@*&\\ \t") || strings.Contains(v, "//") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// signalContext is cancelled on SIGINT or SIGTERM
+func signalContext(logger *logging.Logger) context.Context {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	shutdownC := make(chan os.Signal, 1)
+	signal.Notify(shutdownC, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-shutdownC
+		logger.Info("received signal, shutting down", "signal", sig)
+		cancelFunc()
+	}()
+	return ctx
+}
+
+// once runs the speed test of speedtest.client once and prints its results to stdout, one of every provider of
+// COMPOSITE client. It exits with code 1, when any of the providers failed
+func once(args []string) {
+	flags := flag.NewFlagSet("once", flag.ExitOnError)
+	source := addConfigFlags(flags)
+	format := flags.String("format", "text", "format of the result: text or json")
+	_ = flags.Parse(args)
+	if *format != "text" && *format != "json" {
+		logging.Default().Fatal("unsupported format of the result", "format", *format)
+	}
+
+	cfg := source.mustLoad()
+	logger, err := createLogger(cfg)
+	if err != nil {
+		logging.Default().Fatal("could not create logger", "err", err)
+	}
+	stc, err := parseSpeedTestCfg(cfg.GetConfig("speedtest"))
+	if err != nil {
+		logger.Fatal("could not parse speed test cfg", "err", err)
+	}
+	tester, err := createSpeedTester(stc.clientCfg)
+	if err != nil {
+		logger.Fatal("could not create speed tester", "err", err)
+	}
+
+	speeds, errs := core.TestAll(signalContext(logger), tester)
+	for i, speed := range speeds {
+		if i > 0 && *format == "text" {
+			fmt.Fprintln(os.Stdout)
+		}
+		if err := printSpeed(os.Stdout, speed, *format); err != nil {
+			logger.Fatal("could not print result", "err", err)
+		}
+	}
+	for _, err := range errs {
+		logger.Error("speed test failed", "err", err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printSpeed(w io.Writer, speed core.Speed, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(struct {
+			Timestamp time.Time         `json:"timestamp"`
+			Download  float64           `json:"download"`
+			Upload    float64           `json:"upload"`
+			Ping      float64           `json:"ping"`
+			Tags      map[string]string `json:"tags,omitempty"`
+		}{speed.Timestamp, speed.Download, speed.Upload, float64(speed.Ping) / float64(time.Millisecond), speed.Tags})
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Time:     %s\n", speed.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(b, "Download: %.2f Mbps\n", speed.Download)
+	fmt.Fprintf(b, "Upload:   %.2f Mbps\n", speed.Upload)
+	fmt.Fprintf(b, "Ping:     %v\n", speed.Ping)
+	keys := make([]string, 0, len(speed.Tags))
+	for k := range speed.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "Tag:      %s=%s\n", k, speed.Tags[k])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// configCommand validates or prints the config
+func configCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Usage: speedtest config validate|print [flags]\n")
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ExitOnError)
+	source := addConfigFlags(flags)
+	switch args[0] {
+	case "validate":
+		_ = flags.Parse(args[1:])
+		errs := validateConfig(source.mustLoad())
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("config %s is valid\n", source.path)
+	case "print":
+		_ = flags.Parse(args[1:])
+		if err := printConfig(os.Stdout, source.mustLoad()); err != nil {
+			logging.Default().Fatal("could not print config", "err", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command: %s\nUsage: speedtest config validate|print [flags]\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// validateConfig parses every block of the config and creates components, which do not connect anywhere on their
+// own. Errors of all blocks are returned
+func validateConfig(cfg *hocon.Config) []error {
+	logger := logging.Default()
+	checks := []struct {
+		block string
+		check func() error
+	}{
+		{"logging", func() error {
+			lCfg, err := parseLoggingCfg(cfg)
+			if err != nil {
+				return err
+			}
+			l, err := logging.New(lCfg)
+			if err != nil {
+				return err
+			}
+			logger = l
+			return nil
+		}},
+		{"speedtest", func() error {
+			stc, err := parseSpeedTestCfg(cfg.GetConfig("speedtest"))
+			if err != nil {
+				return err
+			}
+			_, err = createJobs(stc, logger)
+			return err
+		}},
+		{"storage", func() error {
+			storage, err := createStorage(cfg.GetConfig("storage"), logger)
+			if err != nil {
+				return err
+			}
+			return storage.Close()
+		}},
+		{"errors", func() error {
+			_, _, err := createErrorHandler(cfg, errHandlers.NewStructured(logger), core.NewBus(), func(error) {}, logger)
+			return err
+		}},
+		{"outage", func() error {
+			_, err := parseOutageCfg(cfg)
+			return err
+		}},
+		{"alerts", func() error {
+			aCfg, err := parseAlertsCfg(cfg)
+			if err != nil || !aCfg.enabled {
+				return err
+			}
+			_, err = createNotifiers(cfg, aCfg.notifiers, logger)
+			return err
+		}},
+		{"digest", func() error {
+			dCfg, err := parseDigestCfg(cfg)
+			if err != nil || !dCfg.enabled {
+				return err
+			}
+			_, err = createMailer(cfg)
+			return err
+		}},
+		{"shutdown", func() error {
+			_, err := parseGracePeriod(cfg)
+			return err
+		}},
+		{"server", func() error {
+			sCfg, err := parseServerCfg(cfg)
+			if err != nil {
+				return err
+			}
+			if sCfg == nil && parsePrometheusCfg(cfg).enabled {
+				return errors.New("prometheus endpoint needs enabled server")
+			}
+			return nil
+		}},
+		{"health", func() error {
+			hCfg, err := parseHealthCfg(cfg)
+			if err != nil {
+				return err
+			}
+			if hCfg.enabled && !cfg.GetBoolean("server.enabled") {
+				return errors.New("health endpoints need enabled server")
+			}
+			return nil
+		}},
+		{"ui", func() error {
+			if cfg.GetBoolean("ui.enabled") && !cfg.GetBoolean("server.enabled") {
+				return errors.New("ui needs enabled server")
+			}
+			return nil
+		}},
+		{"tracing", func() error {
+			_, err := parseTracingCfg(cfg)
+			return err
+		}},
+		{"otlp-metrics", func() error {
+			_, err := parseOTLPMetricsCfg(cfg)
+			return err
+		}},
+		{"report", func() error {
+			_, err := parseReportCfg(cfg, "")
+			return err
+		}},
+	}
+
+	errs := make([]error, 0)
+	for _, c := range checks {
+		if err := check(c.check); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.block, err))
+		}
+	}
+	return errs
+}
+
+// check turns panics of the config, e.g. when the value has wrong type, into errors
+func check(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return f()
+}
+
+// secretKeys are keys of the config, whose values are replaced with redacted when the config is printed
+var secretKeys = map[string]bool{"token": true, "password": true, "secret": true, "authorization": true}
+
+const redacted = "REDACTED"
+
+// printConfig prints the config as HOCON with keys sorted, so it can be compared and parsed again. Values of
+// secretKeys are redacted, unless they are empty
+func printConfig(w io.Writer, cfg *hocon.Config) error {
+	root, ok := cfg.GetRoot().(hocon.Object)
+	if !ok {
+		return fmt.Errorf("root of the config must be an object, actual: %v", cfg.GetRoot().Type())
+	}
+	b := &strings.Builder{}
+	writeFields(b, root, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFields(b *strings.Builder, o hocon.Object, indent string) {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(indent + k)
+		if _, ok := o[k].(hocon.Object); ok {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(" = ")
+		}
+		v := o[k]
+		if secretKeys[strings.ToLower(k)] && !isEmpty(v) {
+			v = hocon.String(redacted)
+		}
+		writeValue(b, v, indent)
+		b.WriteString("\n")
+	}
+}
+
+func isEmpty(v hocon.Value) bool {
+	s, ok := v.(hocon.String)
+	return ok && s == ""
+}
+
+func writeValue(b *strings.Builder, v hocon.Value, indent string) {
+	switch v := v.(type) {
+	case hocon.Object:
+		b.WriteString("{\n")
+		writeFields(b, v, indent+"  ")
+		b.WriteString(indent + "}")
+	case hocon.Array:
+		if len(v) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, e := range v {
+			b.WriteString(indent + "  ")
+			writeValue(b, e, indent+"  ")
+			if i < len(v)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	case hocon.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case hocon.Duration:
+		b.WriteString(formatDuration(time.Duration(v)))
+	default:
+		b.WriteString(v.String())
+	}
+}
+
+// formatDuration uses the largest unit of HOCON, which represents the duration exactly, e.g. 90s instead of 1m30s
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}, {"ms", time.Millisecond}, {"us", time.Microsecond}}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
+// printVersion prints version set at build time or version of the module, when the binary was installed with go install
+func printVersion() {
+	v := version
+	if v == "" {
+		v = "devel"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+	fmt.Printf("speedtest %s %s %s/%s\n", v, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/gurkankaymak/hocon"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigSource_Overrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.conf")
+	conf := `storage {
+  type = INFLUX
+  influxdb {
+    host = "localhost"
+    port = 8086
+  }
+}
+speedtest.scheduler.duration = 1m
+`
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &configSource{path: path}
+	for _, o := range []string{
+		"storage.type=IN-MEMORY",
+		"storage.influxdb.host=http://influx:8086",
+		"storage.influxdb.points.tags=connection:lte,room:attic",
+		"storage.influxdb.port=9999",
+		"speedtest.scheduler.duration=5m",
+		"alerts.notifiers=[{type = LOG}]",
+		`email.from="Speed Test <speedtest@example.com>"`,
+		"email.subject=slow download",
+	} {
+		if err := source.overrides.Set(o); err != nil {
+			t.Fatalf("could not set override: %s: %v", o, err)
+		}
+	}
+	cfg, err := source.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"storage.type":                 "IN-MEMORY",
+		"storage.influxdb.host":        "http://influx:8086",
+		"storage.influxdb.points.tags": "connection:lte,room:attic",
+		"email.from":                   "Speed Test <speedtest@example.com>",
+		"email.subject":                "slow download",
+	} {
+		if actual := cfg.GetString(path); actual != expected {
+			t.Errorf("expected %s: %q, actual: %q", path, expected, actual)
+		}
+	}
+	if port := cfg.GetInt("storage.influxdb.port"); port != 9999 {
+		t.Errorf("expected port: 9999, actual: %d", port)
+	}
+	if d := cfg.GetDuration("speedtest.scheduler.duration"); d != 5*time.Minute {
+		t.Errorf("expected duration: 5m, actual: %v", d)
+	}
+	if notifiers := cfg.GetArray("alerts.notifiers"); len(notifiers) != 1 {
+		t.Errorf("expected 1 notifier, actual: %v", notifiers)
+	}
+}
+
+func TestOverrides_Set(t *testing.T) {
+	for _, invalid := range []string{"storage.type", "=IN-MEMORY"} {
+		o := &overrides{}
+		if err := o.Set(invalid); err == nil {
+			t.Errorf("expected error for override: %s", invalid)
+		}
+	}
+}
+
+func TestConfigSource_Reference(t *testing.T) {
+	for _, path := range []string{"reference.conf", "reference_env_walkaround.conf"} {
+		if _, err := (&configSource{path: path}).load(); err != nil {
+			t.Errorf("could not load %s: %v", path, err)
+		}
+	}
+}
+
+func TestPrintConfig_RedactsSecrets(t *testing.T) {
+	cfg, err := hocon.ParseString(`storage.influxdb.token = "influx-token"
+email {username = admin, password = "smtp-password"}
+errors.webhook {secret = "", headers {Authorization = "Bearer webhook-token"}}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	if err := printConfig(b, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"influx-token", "smtp-password", "webhook-token"} {
+		if strings.Contains(b.String(), secret) {
+			t.Errorf("expected %s to be redacted in:\n%s", secret, b)
+		}
+	}
+	for _, expected := range []string{`token = "REDACTED"`, `username = "admin"`, `secret = ""`} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("expected %s in:\n%s", expected, b)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := map[string]struct {
+		overrides []string
+		invalid   string
+	}{
+		"reference":                 {},
+		"tracing with grpc":         {overrides: []string{"tracing.enabled=true", "tracing.exporter=otlp-grpc"}},
+		"unsupported exporter":      {overrides: []string{"tracing.enabled=true", "tracing.exporter=jaeger"}, invalid: "tracing"},
+		"health without server":     {overrides: []string{"server.enabled=false", "prometheus.enabled=false", "ui.enabled=false"}, invalid: "health"},
+		"ui without server":         {overrides: []string{"server.enabled=false", "prometheus.enabled=false", "health.enabled=false"}, invalid: "ui"},
+		"unsupported OTLP protocol": {overrides: []string{"otlp-metrics.enabled=true", "otlp-metrics.protocol=udp"}, invalid: "otlp-metrics"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// InfluxDB port of the reference configuration is set only by the environment
+			errs := validateConfig(loadConfig(t, append([]string{"storage.type=IN-MEMORY"}, tt.overrides...)...))
+			if tt.invalid == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected valid config, actual errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), tt.invalid+":") {
+				t.Fatalf("expected error of %s block, actual: %v", tt.invalid, errs)
+			}
+		})
+	}
+}
+
+func TestReference_DefaultEndpoints(t *testing.T) {
+	cfg := loadConfig(t, "tracing.enabled=true", "tracing.exporter=otlp-grpc", "otlp-metrics.enabled=true", "otlp-metrics.protocol=grpc")
+	tCfg, err := parseTracingCfg(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oCfg, err := parseOTLPMetricsCfg(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// empty endpoint is the default of the exporter, so it fits both OTLP protocols
+	if tCfg.cfg.Endpoint != "" || oCfg.cfg.Endpoint != "" {
+		t.Fatalf("expected empty endpoints, actual tracing: %q, OTLP metrics: %q", tCfg.cfg.Endpoint, oCfg.cfg.Endpoint)
+	}
+}
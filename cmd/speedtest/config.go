@@ -4,12 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/bind"
+	"github.com/paluszkiewiczB/speedtest/internal/command"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/dummy"
+	"github.com/paluszkiewiczB/speedtest/internal/httpspeed"
 	"github.com/paluszkiewiczB/speedtest/internal/influx"
-	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"github.com/paluszkiewiczB/speedtest/internal/iperf3"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
 	"github.com/paluszkiewiczB/speedtest/internal/ookla"
-	"log"
+	"github.com/paluszkiewiczB/speedtest/internal/outage"
 	"strings"
 	"time"
 )
@@ -17,9 +21,12 @@ import (
 type prometheusCfg struct {
 	enabled        bool
 	storageEnabled bool
-	cfg            observe.PrometheusConfig
+	errorsEnabled  bool
+	queueEnabled   bool
+	endpoint       string
 }
 
+// parsePrometheusCfg parses prometheus block. Metrics are served at the endpoint of the server
 func parsePrometheusCfg(config *hocon.Config) prometheusCfg {
 	pCfg := config.GetConfig("prometheus")
 	enabled := pCfg.GetBoolean("enabled")
@@ -27,19 +34,16 @@ func parsePrometheusCfg(config *hocon.Config) prometheusCfg {
 		return prometheusCfg{enabled: false}
 	}
 
-	cfg := observe.PrometheusConfig{
-		Endpoint: pCfg.GetString("endpoint"),
-		Port:     pCfg.GetInt("port"),
-	}
-
 	return prometheusCfg{
 		enabled:        true,
-		cfg:            cfg,
+		endpoint:       pCfg.GetString("endpoint"),
 		storageEnabled: pCfg.GetBoolean("storage"),
+		errorsEnabled:  pCfg.GetBoolean("errors"),
+		queueEnabled:   pCfg.GetBoolean("queue"),
 	}
 }
 
-func createStorage(cfg *hocon.Config) (core.Storage, error) {
+func createStorage(cfg *hocon.Config, logger *logging.Logger) (core.Storage, error) {
 	storageType := cfg.GetString("type")
 	switch storageType {
 	case "INFLUX":
@@ -47,28 +51,31 @@ func createStorage(cfg *hocon.Config) (core.Storage, error) {
 		if err != nil {
 			return nil, err
 		}
+		c.Logger = logger
 		client, err := influx.NewClient(c)
 		return client, err
 	case "IN-MEMORY":
 		return dummy.NewStorage(), nil
+	case "WEBHOOK":
+		return createWebhook(cfg.GetConfig("webhook"), logger)
 	case "TIMEOUT":
-		return createTimeoutStorage(cfg)
+		return createTimeoutStorage(cfg, logger)
 	case "RETRY":
-		return createRetryStorage(cfg)
+		return createRetryStorage(cfg, logger)
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
 	}
 }
 
-func createTimeoutStorage(cfg *hocon.Config) (core.Storage, error) {
-	return createInfluxDecorator(cfg, func(delegate influx.Client, cfg *hocon.Config) (influx.Client, error) {
+func createTimeoutStorage(cfg *hocon.Config, logger *logging.Logger) (core.Storage, error) {
+	return createInfluxDecorator(cfg, logger, func(delegate influx.Client, cfg *hocon.Config) (influx.Client, error) {
 		maxTime := cfg.GetConfig("timeout").GetDuration("time")
 		return &influx.TimeOutingClient{Max: maxTime, Delegate: delegate}, nil
 	})
 }
 
-func createRetryStorage(cfg *hocon.Config) (core.Storage, error) {
-	return createInfluxDecorator(cfg, func(delegate influx.Client, cfg *hocon.Config) (influx.Client, error) {
+func createRetryStorage(cfg *hocon.Config, logger *logging.Logger) (core.Storage, error) {
+	return createInfluxDecorator(cfg, logger, func(delegate influx.Client, cfg *hocon.Config) (influx.Client, error) {
 		rCfg := cfg.GetConfig("retry")
 		tries := rCfg.GetInt("tries")
 		interval := rCfg.GetDuration("interval")
@@ -76,8 +83,8 @@ func createRetryStorage(cfg *hocon.Config) (core.Storage, error) {
 	})
 }
 
-func createInfluxDecorator(cfg *hocon.Config, f func(influx.Client, *hocon.Config) (influx.Client, error)) (influx.Client, error) {
-	delegate, err := createStorage(cfg.GetConfig("client"))
+func createInfluxDecorator(cfg *hocon.Config, logger *logging.Logger, f func(influx.Client, *hocon.Config) (influx.Client, error)) (influx.Client, error) {
+	delegate, err := createStorage(cfg.GetConfig("client"), logger)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +104,9 @@ func parseInfluxStorageCfg(config *hocon.Config) (influx.Cfg, error) {
 		Organization: config.GetString("organization"),
 		Bucket:       config.GetString("bucket"),
 		Points: influx.PointsCfg{
-			Measurement: points.GetString("measurement"),
-			Tags:        parseTags(points.GetString("tags")),
+			Measurement:       points.GetString("measurement"),
+			OutageMeasurement: points.GetString("outage-measurement"),
+			Tags:              parseTags(points.GetString("tags")),
 		}}, nil
 }
 
@@ -114,7 +122,7 @@ func parseTags(tags string) map[string]string {
 		value := split[1]
 
 		if old, ok := out[key]; ok {
-			log.Printf("overriding tag value for key: %s from %s to %s", key, old, value)
+			logging.Default().Named("config").Warn("overriding tag value", "key", key, "old", old, "new", value)
 		}
 
 		out[key] = value
@@ -125,6 +133,15 @@ func parseTags(tags string) map[string]string {
 type speedTestCfg struct {
 	schedulerCfg *schedulerCfg
 	clientCfg    *clientCfg
+	interfaces   []interfaceCfg
+	jobs         []jobCfg
+	queueCfg     core.QueueConfig
+}
+
+// interfaceCfg is a separate schedule of the client bound to the network interface
+type interfaceCfg struct {
+	name string
+	bind bind.Cfg
 }
 
 func parseSpeedTestCfg(config *hocon.Config) (*speedTestCfg, error) {
@@ -138,26 +155,221 @@ func parseSpeedTestCfg(config *hocon.Config) (*speedTestCfg, error) {
 		return nil, err
 	}
 
+	interfaces, err := parseInterfaces(config)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := parseJobs(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		for _, i := range interfaces {
+			if j.name == i.name {
+				return nil, fmt.Errorf("duplicated job name: %s, it is also the name of an interface", j.name)
+			}
+		}
+	}
+
+	queueCfg, err := parseQueueCfg(config.GetConfig("queue"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &speedTestCfg{
 		schedulerCfg: sCfg,
 		clientCfg:    cCfg,
+		interfaces:   interfaces,
+		jobs:         jobs,
+		queueCfg:     queueCfg,
 	}, nil
 }
 
+// logTo makes speed testers created from the configuration log with logger
+func (c *speedTestCfg) logTo(logger *logging.Logger) {
+	c.clientCfg.logTo(logger)
+	for _, j := range c.jobs {
+		j.clientCfg.logTo(logger)
+	}
+}
+
+// publishTo makes speed testers created from the configuration publish events on bus
+func (c *speedTestCfg) publishTo(bus *core.Bus) {
+	c.clientCfg.ooklaCfg.Bus = bus
+	for _, j := range c.jobs {
+		j.clientCfg.ooklaCfg.Bus = bus
+	}
+}
+
+// parseQueueCfg parses optional configuration of the queue of results. Missing queue is core.DefaultQueueConfig
+func parseQueueCfg(cfg *hocon.Config) (core.QueueConfig, error) {
+	if cfg == nil {
+		return core.DefaultQueueConfig, nil
+	}
+
+	overflow := core.OverflowPolicy(cfg.GetString("overflow"))
+	switch overflow {
+	case "", core.Block, core.DropOldest, core.DropNewest:
+	default:
+		return core.QueueConfig{}, fmt.Errorf("unsupported queue overflow policy: %s", overflow)
+	}
+	capacity := cfg.GetInt("capacity")
+	if capacity < 0 {
+		return core.QueueConfig{}, fmt.Errorf("queue capacity cannot be negative: %d", capacity)
+	}
+	// full unbuffered queue drops nearly every result, so only blocking queue can be unbuffered
+	if capacity < 1 && (overflow == core.DropOldest || overflow == core.DropNewest) {
+		return core.QueueConfig{}, fmt.Errorf("queue capacity must be at least 1 with overflow policy: %s, actual: %d", overflow, capacity)
+	}
+	return core.QueueConfig{Capacity: capacity, Overflow: overflow}, nil
+}
+
+// jobCfg is a named speed test with its own client, schedule, tags and storage
+type jobCfg struct {
+	name         string
+	schedulerCfg *schedulerCfg
+	clientCfg    *clientCfg
+	tags         map[string]string
+	// storage is nil when the job pushes results to the default storage
+	storage *hocon.Config
+}
+
+// parseJobs parses optional list of jobs. Client and scheduler of the job fall back to the ones of speedtest block
+func parseJobs(cfg *hocon.Config) ([]jobCfg, error) {
+	jobs := make([]jobCfg, 0)
+	names := make(map[string]bool)
+	for _, j := range cfg.GetArray("jobs") {
+		obj, ok := j.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("job must be an object, actual: %v", j)
+		}
+		jCfg := obj.ToConfig()
+		name := jCfg.GetString("name")
+		if name == "" {
+			return nil, fmt.Errorf("job must have a name: %v", j)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicated job name: %s", name)
+		}
+		names[name] = true
+
+		sCfg, err := parseSchedulerCfg(withFallback(jCfg.GetConfig("scheduler"), cfg.GetConfig("scheduler")))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse scheduler of job: %s: %w", name, err)
+		}
+		cCfg, err := parseClientCfg(withFallback(jCfg.GetConfig("client"), cfg.GetConfig("client")))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse client of job: %s: %w", name, err)
+		}
+		tags := make(map[string]string)
+		if t := jCfg.GetString("tags"); t != "" {
+			tags = parseTags(t)
+		}
+		jobs = append(jobs, jobCfg{
+			name:         name,
+			schedulerCfg: sCfg,
+			clientCfg:    cCfg,
+			tags:         tags,
+			storage:      jCfg.GetConfig("storage"),
+		})
+	}
+	return jobs, nil
+}
+
+// withFallback returns cfg merged with fallback, or fallback when cfg is missing
+func withFallback(cfg, fallback *hocon.Config) *hocon.Config {
+	if cfg == nil {
+		return fallback
+	}
+	return cfg.WithFallback(fallback)
+}
+
+// createJobs creates jobs declared in speedtest.jobs followed by jobs testing speedtest.interfaces.
+// Storage of the job is nil, unless it declares its own storage chain
+func createJobs(cfg *speedTestCfg, logger *logging.Logger) ([]core.Job, error) {
+	jobs := make([]core.Job, 0, len(cfg.jobs)+len(cfg.interfaces))
+	for _, j := range cfg.jobs {
+		tester, err := createSpeedTester(j.clientCfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not create speed tester of job: %s: %w", j.name, err)
+		}
+		job := core.Job{Name: j.name, Interval: j.schedulerCfg.duration, Tester: tester, Tags: j.tags}
+		if j.storage != nil {
+			job.Storage, err = createStorage(j.storage, logger)
+			if err != nil {
+				return nil, fmt.Errorf("could not create storage of job: %s: %w", j.name, err)
+			}
+		}
+		jobs = append(jobs, job)
+	}
+
+	for _, i := range cfg.interfaces {
+		tester, err := createSpeedTester(cfg.clientCfg.withBind(i.bind))
+		if err != nil {
+			return nil, fmt.Errorf("could not create speed tester for interface: %s: %w", i.name, err)
+		}
+		jobs = append(jobs, core.Job{
+			Name:     i.name,
+			Interval: cfg.schedulerCfg.duration,
+			Tester:   tester,
+			Tags:     map[string]string{"interface": i.name},
+		})
+	}
+	return jobs, nil
+}
+
+// parseInterfaces parses optional list of network interfaces. Every interface is tested on its own schedule
+func parseInterfaces(cfg *hocon.Config) ([]interfaceCfg, error) {
+	interfaces := make([]interfaceCfg, 0)
+	names := make(map[string]bool)
+	for _, i := range cfg.GetArray("interfaces") {
+		obj, ok := i.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("interface must be an object, actual: %v", i)
+		}
+		iCfg := obj.ToConfig()
+		name := iCfg.GetString("name")
+		if name == "" {
+			return nil, fmt.Errorf("interface must have a name: %v", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicated interface name: %s", name)
+		}
+		names[name] = true
+		b := parseBindCfg(iCfg)
+		if !b.Enabled() {
+			return nil, fmt.Errorf("interface: %s requires address or interface", name)
+		}
+		interfaces = append(interfaces, interfaceCfg{name: name, bind: b})
+	}
+	return interfaces, nil
+}
+
+// parseBindCfg parses optional source address and network interface of outgoing connections
+func parseBindCfg(cfg *hocon.Config) bind.Cfg {
+	if cfg == nil {
+		return bind.Cfg{}
+	}
+	return bind.Cfg{Address: cfg.GetString("address"), Interface: cfg.GetString("interface")}
+}
+
 type schedulerCfg struct {
 	duration time.Duration
 }
 
 func parseSchedulerCfg(cfg *hocon.Config) (*schedulerCfg, error) {
-	duration, err := parseDuration(cfg)
+	duration, err := parseDuration(cfg, "duration")
 	if err != nil {
 		return nil, err
 	}
 	return &schedulerCfg{duration: duration}, nil
 }
 
-func parseDuration(cfg *hocon.Config) (time.Duration, error) {
-	duration := cfg.Get("duration")
+// parseDuration parses duration at given path. Durations overridden with environment variables are strings, so they
+// have to be parsed separately
+func parseDuration(cfg *hocon.Config, path string) (time.Duration, error) {
+	duration := cfg.Get(path)
 	switch d := duration.(type) {
 	case hocon.String:
 		cheat, err := hocon.ParseString(fmt.Sprintf("duration: %s", d))
@@ -166,29 +378,365 @@ func parseDuration(cfg *hocon.Config) (time.Duration, error) {
 		}
 		return cheat.GetDuration("duration"), nil
 	case hocon.Duration:
-		return cfg.GetDuration("duration"), nil
+		return cfg.GetDuration(path), nil
 	}
 
-	return -1, errors.New("unsupported value type of duration")
+	return -1, fmt.Errorf("unsupported value type of %s", path)
 }
 
 type clientCfg struct {
 	clientType string
+	ooklaCfg   ookla.Cfg
+	httpCfg    httpspeed.Cfg
+	lanCfg     httpspeed.Cfg
+	iperf3Cfg  iperf3.Cfg
+	execCfg    command.Cfg
+	providers  []providerCfg
+	bind       bind.Cfg
+}
+
+// providerCfg is a tester of composite client. It shares configuration with other testers of the client
+type providerCfg struct {
+	name, clientType string
 }
 
 func parseClientCfg(cfg *hocon.Config) (*clientCfg, error) {
-	return &clientCfg{clientType: cfg.GetString("type")}, nil
+	timeouts, err := parseClientTimeouts(cfg.GetConfig("timeouts"))
+	if err != nil {
+		return nil, err
+	}
+	httpCfg, err := parseHttpClientCfg(cfg.GetConfig("http"))
+	if err != nil {
+		return nil, err
+	}
+	httpCfg.Timeouts = httpspeed.Timeouts{Ping: timeouts.Ping, Download: timeouts.Download, Upload: timeouts.Upload}
+	iperf3Cfg, err := parseIperf3ClientCfg(cfg.GetConfig("iperf3"))
+	if err != nil {
+		return nil, err
+	}
+	iperf3Cfg.Timeouts = iperf3.Timeouts{Ping: timeouts.Ping, Download: timeouts.Download, Upload: timeouts.Upload}
+	execCfg, err := parseExecClientCfg(cfg.GetConfig("exec"))
+	if err != nil {
+		return nil, err
+	}
+	providers, err := parseProviders(cfg.GetConfig("composite"))
+	if err != nil {
+		return nil, err
+	}
+	return &clientCfg{
+		clientType: cfg.GetString("type"),
+		ooklaCfg:   ookla.Cfg{Timeouts: timeouts},
+		httpCfg:    httpCfg,
+		lanCfg:     parseLanClientCfg(cfg.GetConfig("lan"), httpCfg),
+		iperf3Cfg:  iperf3Cfg,
+		execCfg:    execCfg,
+		providers:  providers,
+		bind:       parseBindCfg(cfg.GetConfig("bind")),
+	}, nil
+}
+
+// parseProviders parses optional list of testers run by composite client
+func parseProviders(cfg *hocon.Config) ([]providerCfg, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	providers := make([]providerCfg, 0)
+	for _, p := range cfg.GetArray("providers") {
+		obj, ok := p.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("composite provider must be an object, actual: %v", p)
+		}
+		pCfg := obj.ToConfig()
+		providers = append(providers, providerCfg{name: pCfg.GetString("name"), clientType: pCfg.GetString("type")})
+	}
+	return providers, nil
+}
+
+// parseExecClientCfg parses optional configuration of client running external command
+func parseExecClientCfg(cfg *hocon.Config) (command.Cfg, error) {
+	if cfg == nil {
+		return command.Cfg{}, nil
+	}
+
+	timeout, err := parseDuration(cfg, "timeout")
+	if err != nil {
+		return command.Cfg{}, err
+	}
+	mapping := cfg.GetConfig("mapping")
+	if mapping == nil {
+		return command.Cfg{}, errors.New("missing mapping of command output")
+	}
+	return command.Cfg{
+		Command: cfg.GetString("command"),
+		Args:    cfg.GetStringSlice("args"),
+		Timeout: timeout,
+		Format:  command.Format(cfg.GetString("format")),
+		Mapping: command.Mapping{
+			Download:  parseField(mapping.GetConfig("download")),
+			Upload:    parseField(mapping.GetConfig("upload")),
+			Ping:      parseField(mapping.GetConfig("ping")),
+			Timestamp: parseField(mapping.GetConfig("timestamp")),
+		},
+	}, nil
+}
+
+func parseField(cfg *hocon.Config) command.Field {
+	if cfg == nil {
+		return command.Field{}
+	}
+	return command.Field{Path: cfg.GetString("path"), Scale: parseFloat(cfg, "scale")}
+}
+
+// parseFloat parses number at given path. Numbers without fraction are parsed by hocon as integers
+func parseFloat(cfg *hocon.Config, path string) float64 {
+	if i, ok := cfg.Get(path).(hocon.Int); ok {
+		return float64(i)
+	}
+	return cfg.GetFloat64(path)
+}
+
+// parseIperf3ClientCfg parses optional configuration of iperf3 client
+func parseIperf3ClientCfg(cfg *hocon.Config) (iperf3.Cfg, error) {
+	if cfg == nil {
+		return iperf3.Cfg{}, nil
+	}
+
+	duration, err := parseDuration(cfg, "duration")
+	if err != nil {
+		return iperf3.Cfg{}, err
+	}
+	return iperf3.Cfg{
+		Address:   cfg.GetString("address"),
+		Streams:   cfg.GetInt("streams"),
+		Duration:  duration,
+		BlockSize: cfg.GetInt("block-size"),
+	}, nil
+}
+
+// parseLanClientCfg parses optional configuration of client testing speed against other instance running serve
+// subcommand. Except URLs, it is the same as configuration of HTTP client
+func parseLanClientCfg(cfg *hocon.Config, httpCfg httpspeed.Cfg) httpspeed.Cfg {
+	if cfg == nil {
+		return httpCfg
+	}
+	return httpspeed.ForServer(cfg.GetString("url"), int64(cfg.GetInt("download-size")), httpCfg)
+}
+
+// parseHttpClientCfg parses optional configuration of HTTP speed tester
+func parseHttpClientCfg(cfg *hocon.Config) (httpspeed.Cfg, error) {
+	if cfg == nil {
+		return httpspeed.Cfg{}, nil
+	}
+
+	warmUp := time.Duration(0)
+	if cfg.Get("warm-up") != nil {
+		d, err := parseDuration(cfg, "warm-up")
+		if err != nil {
+			return httpspeed.Cfg{}, err
+		}
+		warmUp = d
+	}
+
+	return httpspeed.Cfg{
+		DownloadUrl: cfg.GetString("download-url"),
+		UploadUrl:   cfg.GetString("upload-url"),
+		PingUrl:     cfg.GetString("ping-url"),
+		UploadSize:  int64(cfg.GetInt("upload-size")),
+		Streams:     cfg.GetInt("streams"),
+		WarmUp:      warmUp,
+		PingCount:   cfg.GetInt("ping-count"),
+	}, nil
+}
+
+// parseClientTimeouts parses optional timeouts of speed test phases. Missing timeout means no limit
+func parseClientTimeouts(cfg *hocon.Config) (ookla.Timeouts, error) {
+	timeouts := ookla.Timeouts{}
+	if cfg == nil {
+		return timeouts, nil
+	}
+
+	fields := map[string]*time.Duration{
+		"user-info":   &timeouts.UserInfo,
+		"server-list": &timeouts.ServerList,
+		"ping":        &timeouts.Ping,
+		"download":    &timeouts.Download,
+		"upload":      &timeouts.Upload,
+	}
+	for path, field := range fields {
+		if cfg.Get(path) == nil {
+			continue
+		}
+		d, err := parseDuration(cfg, path)
+		if err != nil {
+			return timeouts, err
+		}
+		*field = d
+	}
+	return timeouts, nil
 }
 
 func createSpeedTester(cfg *clientCfg) (core.SpeedTester, error) {
+	if cfg.bind.Enabled() {
+		bound, err := bindClient(*cfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not bind speed tester to: %s: %w", cfg.bind, err)
+		}
+		cfg = bound
+	}
+
 	switch cfg.clientType {
 	case "OOKLA":
-		return ookla.NewSpeedTester(), nil
+		return ookla.NewSpeedTester(cfg.ooklaCfg), nil
 	case "OOKLA_LOGGING":
-		return ookla.Logging(ookla.NewSpeedTester()), nil
+		return ookla.Logging(ookla.NewSpeedTester(cfg.ooklaCfg)), nil
+	case "HTTP":
+		return httpspeed.NewSpeedTester(cfg.httpCfg), nil
+	case "LAN":
+		return httpspeed.NewSpeedTester(cfg.lanCfg), nil
+	case "IPERF3":
+		return iperf3.NewSpeedTester(cfg.iperf3Cfg), nil
+	case "EXEC":
+		return command.NewSpeedTester(cfg.execCfg), nil
+	case "COMPOSITE":
+		return createCompositeTester(cfg)
 	case "DUMMY":
 		return &dummy.SpeedTester{}, nil
 	}
 
 	return nil, fmt.Errorf("unsupported speed tester type: %s", cfg.clientType)
 }
+
+// bindClient makes testers connecting over the network use source address and interface of cfg.
+// Returned configuration has binding already applied
+func bindClient(cfg clientCfg) (*clientCfg, error) {
+	client, err := bind.Client(cfg.bind)
+	if err != nil {
+		return nil, err
+	}
+	dialer, err := bind.Dialer(cfg.bind)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ooklaCfg.Client = client
+	cfg.httpCfg.Client = client
+	cfg.lanCfg.Client = client
+	cfg.iperf3Cfg.Dialer = dialer
+	cfg.bind = bind.Cfg{}
+	return &cfg, nil
+}
+
+// logTo sets logger of all testers of the client
+func (c *clientCfg) logTo(logger *logging.Logger) {
+	c.ooklaCfg.Logger = logger
+	c.httpCfg.Logger = logger
+	c.lanCfg.Logger = logger
+	c.iperf3Cfg.Logger = logger
+}
+
+// withBind returns copy of cfg bound to given source
+func (c clientCfg) withBind(b bind.Cfg) *clientCfg {
+	c.bind = b
+	return &c
+}
+
+func createCompositeTester(cfg *clientCfg) (core.SpeedTester, error) {
+	if len(cfg.providers) == 0 {
+		return nil, errors.New("composite speed tester requires at least one provider")
+	}
+
+	testers := make([]core.NamedTester, 0, len(cfg.providers))
+	for _, p := range cfg.providers {
+		if p.clientType == "COMPOSITE" {
+			return nil, fmt.Errorf("composite provider: %s cannot be composite", p.name)
+		}
+		pCfg := *cfg
+		pCfg.clientType = p.clientType
+		tester, err := createSpeedTester(&pCfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not create composite provider: %s: %w", p.name, err)
+		}
+		testers = append(testers, core.NamedTester{Name: p.name, Tester: tester})
+	}
+	return core.NewComposite(testers...), nil
+}
+
+// parseGracePeriod parses optional time given to running speed tests on shutdown. Missing value means no waiting
+func parseGracePeriod(config *hocon.Config) (time.Duration, error) {
+	sCfg := config.GetConfig("shutdown")
+	if sCfg == nil || sCfg.Get("grace-period") == nil {
+		return 0, nil
+	}
+	return parseDuration(sCfg, "grace-period")
+}
+
+// parsePushTimeout returns timeout of TIMEOUT storage, so pushes during the shutdown wait as long as during the run.
+// Zero is returned for other storages
+func parsePushTimeout(config *hocon.Config) (time.Duration, error) {
+	sCfg := config.GetConfig("storage")
+	if sCfg == nil || sCfg.GetString("type") != "TIMEOUT" {
+		return 0, nil
+	}
+	return parseDuration(sCfg, "timeout.time")
+}
+
+type outageCfg struct {
+	enabled  bool
+	interval time.Duration
+	cfg      outage.Cfg
+}
+
+func parseOutageCfg(config *hocon.Config) (*outageCfg, error) {
+	oCfg := config.GetConfig("outage")
+	if oCfg == nil || !oCfg.GetBoolean("enabled") {
+		return &outageCfg{enabled: false}, nil
+	}
+
+	interval, err := parseDuration(oCfg, "interval")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseDuration(oCfg, "timeout")
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 || timeout <= 0 {
+		return nil, fmt.Errorf("outage interval: %v and timeout: %v must be positive", interval, timeout)
+	}
+
+	probes := make([]outage.Probe, 0)
+	for _, target := range oCfg.GetArray("targets") {
+		obj, ok := target.(hocon.Object)
+		if !ok {
+			return nil, fmt.Errorf("outage target must be an object, actual: %v", target)
+		}
+		probe, err := parseProbe(obj.ToConfig())
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, probe)
+	}
+	if len(probes) == 0 {
+		return nil, errors.New("at least one outage target is required")
+	}
+
+	return &outageCfg{
+		enabled:  true,
+		interval: interval,
+		cfg:      outage.Cfg{Probes: probes, Timeout: timeout},
+	}, nil
+}
+
+func parseProbe(cfg *hocon.Config) (outage.Probe, error) {
+	probeType := cfg.GetString("type")
+	switch probeType {
+	case "TCP":
+		return &outage.TCP{Address: cfg.GetString("address")}, nil
+	case "HTTP":
+		return &outage.HTTP{Url: cfg.GetString("url")}, nil
+	case "DNS":
+		return &outage.DNS{Host: cfg.GetString("host")}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported outage target type: %s", probeType)
+}
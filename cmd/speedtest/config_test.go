@@ -0,0 +1,159 @@
+package main
+
+import (
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueueCfg(t *testing.T) {
+	tests := map[string]struct {
+		conf     string
+		expected core.QueueConfig
+		invalid  bool
+	}{
+		"unbuffered blocking queue": {
+			conf:     "capacity = 0, overflow = block",
+			expected: core.QueueConfig{Capacity: 0, Overflow: core.Block},
+		},
+		"buffered dropping queue": {
+			conf:     "capacity = 8, overflow = drop-oldest",
+			expected: core.QueueConfig{Capacity: 8, Overflow: core.DropOldest},
+		},
+		"unbuffered queue dropping oldest": {conf: "capacity = 0, overflow = drop-oldest", invalid: true},
+		"unbuffered queue dropping newest": {conf: "capacity = 0, overflow = drop-newest", invalid: true},
+		"negative capacity":                {conf: "capacity = -1, overflow = block", invalid: true},
+		"unknown overflow policy":          {conf: "capacity = 1, overflow = retry", invalid: true},
+	}
+
+	t.Run("missing queue", func(t *testing.T) {
+		actual, err := parseQueueCfg(nil)
+		if err != nil || actual != core.DefaultQueueConfig {
+			t.Fatalf("expected: %v, actual: %v, err: %v", core.DefaultQueueConfig, actual, err)
+		}
+	})
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := hocon.ParseString(tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := parseQueueCfg(cfg)
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("expected error, actual: %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tt.expected {
+				t.Fatalf("expected: %v, actual: %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// loadConfig loads the reference configuration without environment variables and with the overrides
+func loadConfig(t *testing.T, overrides ...string) *hocon.Config {
+	t.Helper()
+	source := &configSource{path: "reference_env_walkaround.conf"}
+	for _, o := range overrides {
+		if err := source.overrides.Set(o); err != nil {
+			t.Fatalf("could not set override: %s: %v", o, err)
+		}
+	}
+	cfg, err := source.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestParseReportCfg_Job(t *testing.T) {
+	cfg := loadConfig(t,
+		"speedtest.scheduler.duration=10m",
+		"speedtest.interfaces=[{name = lte, interface = wwan0}]",
+		`speedtest.jobs=[{name = lan, client {type = LAN}, scheduler {duration = 1m}, tags = "link:lan"}, {name = untagged, client {type = LAN}}]`,
+	)
+	tests := map[string]struct {
+		job      string
+		interval time.Duration
+		tags     map[string]string
+		invalid  bool
+	}{
+		"all jobs":     {interval: 10 * time.Minute},
+		"job":          {job: "lan", interval: time.Minute, tags: map[string]string{"link": "lan"}},
+		"interface":    {job: "lte", interval: 10 * time.Minute, tags: map[string]string{"interface": "lte"}},
+		"untagged job": {job: "untagged", invalid: true},
+		"unknown job":  {job: "wifi", invalid: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseReportCfg(cfg, tt.job)
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("expected error, actual: %+v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual.interval != tt.interval || !reflect.DeepEqual(actual.tags, tt.tags) {
+				t.Fatalf("expected interval: %v, tags: %v, actual: %+v", tt.interval, tt.tags, actual)
+			}
+		})
+	}
+}
+
+func TestCreateErrorHandler_Dedup(t *testing.T) {
+	tests := map[string]struct {
+		overrides []string
+		invalid   bool
+	}{
+		"disabled":                   {},
+		"with webhook":               {overrides: []string{"errors.dedup.enabled=true", "errors.webhook.enabled=true"}},
+		"without sink":               {overrides: []string{"errors.dedup.enabled=true"}, invalid: true},
+		"with webhook and no window": {overrides: []string{"errors.dedup.enabled=true", "errors.webhook.enabled=true", "errors.dedup.window=0s"}, invalid: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := loadConfig(t, tt.overrides...)
+			logger := logging.Default()
+			_, _, err := createErrorHandler(cfg, errHandlers.NewStructured(logger), core.NewBus(), func(error) {}, logger)
+			if tt.invalid && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tt.invalid && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestParseSpeedTestCfg_DuplicatedNames(t *testing.T) {
+	tests := map[string][]string{
+		"jobs":       {`speedtest.jobs=[{name = lan, client {type = LAN}}, {name = lan, client {type = HTTP}}]`},
+		"interfaces": {`speedtest.interfaces=[{name = lte, interface = wwan0}, {name = lte, interface = wwan1}]`},
+		"job and interface": {
+			`speedtest.jobs=[{name = lte, client {type = LAN}}]`,
+			`speedtest.interfaces=[{name = lte, interface = wwan0}]`,
+		},
+	}
+	for name, overrides := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSpeedTestCfg(loadConfig(t, overrides...).GetConfig("speedtest"))
+			if err == nil || !strings.Contains(err.Error(), "duplicated") {
+				t.Fatalf("expected error of duplicated name, actual: %v", err)
+			}
+		})
+	}
+}
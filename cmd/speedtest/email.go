@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/email"
+	"strings"
+	"time"
+)
+
+type digestCfg struct {
+	enabled bool
+	cfg     email.DigestCfg
+}
+
+// parseEmailCfg parses SMTP configuration shared by EMAIL alert notifier and the digest
+func parseEmailCfg(config *hocon.Config) (email.Cfg, error) {
+	cfg := config.GetConfig("email")
+	if cfg == nil {
+		return email.Cfg{}, errors.New("missing email configuration")
+	}
+
+	eCfg := email.Cfg{
+		Host:     cfg.GetString("host"),
+		Port:     cfg.GetInt("port"),
+		Username: cfg.GetString("username"),
+		Password: cfg.GetString("password"),
+		From:     cfg.GetString("from"),
+		To:       parseRecipients(cfg),
+		StartTLS: cfg.GetBoolean("starttls"),
+	}
+	if cfg.GetBoolean("insecure-skip-verify") {
+		eCfg.TLS = &tls.Config{ServerName: eCfg.Host, InsecureSkipVerify: true}
+	}
+	if cfg.Get("timeout") != nil {
+		timeout, err := parseDuration(cfg, "timeout")
+		if err != nil {
+			return email.Cfg{}, err
+		}
+		eCfg.Timeout = timeout
+	}
+	return eCfg, nil
+}
+
+// parseRecipients accepts an array or comma separated string, so recipients can be set with environment variable
+func parseRecipients(cfg *hocon.Config) []string {
+	if _, ok := cfg.Get("to").(hocon.Array); ok {
+		return cfg.GetStringSlice("to")
+	}
+	to := make([]string, 0)
+	for _, r := range strings.Split(cfg.GetString("to"), ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			to = append(to, r)
+		}
+	}
+	return to
+}
+
+func parseEmailTemplates(cfg *hocon.Config) email.Templates {
+	if cfg == nil {
+		return email.Templates{}
+	}
+	return email.Templates{
+		Subject: cfg.GetString("subject"),
+		Text:    cfg.GetString("text"),
+		HTML:    cfg.GetString("html"),
+	}
+}
+
+func createMailer(config *hocon.Config) (*email.Mailer, error) {
+	eCfg, err := parseEmailCfg(config)
+	if err != nil {
+		return nil, err
+	}
+	return email.NewMailer(eCfg)
+}
+
+func createEmailNotifier(config, cfg *hocon.Config) (*email.Notifier, error) {
+	mailer, err := createMailer(config)
+	if err != nil {
+		return nil, err
+	}
+	return email.NewNotifier(mailer, parseEmailTemplates(cfg.GetConfig("templates")))
+}
+
+func parseDigestCfg(config *hocon.Config) (*digestCfg, error) {
+	cfg := config.GetConfig("email.digest")
+	if cfg == nil || !cfg.GetBoolean("enabled") {
+		return &digestCfg{enabled: false}, nil
+	}
+
+	at, err := time.Parse("15:04", cfg.GetString("at"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse time of the digest: %w", err)
+	}
+	dCfg := email.DigestCfg{
+		Period:    email.Period(cfg.GetString("period")),
+		At:        time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute,
+		Templates: parseEmailTemplates(cfg.GetConfig("templates")),
+	}
+	if dCfg.Period == email.Weekly {
+		weekday, err := parseWeekday(cfg.GetString("weekday"))
+		if err != nil {
+			return nil, err
+		}
+		dCfg.Weekday = weekday
+	}
+	return &digestCfg{enabled: true, cfg: dCfg}, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported weekday: %s", s)
+}
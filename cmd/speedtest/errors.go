@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"time"
+)
+
+// createErrorHandler adds sinks configured in errors block to the handler and collapses repeated errors passed to
+// the sinks, so dedup requires at least one sink. Consecutive failures of tests and pushes are escalated to exit,
+// which should shut down the process. Escalation is reset by results pushed to the storage, so it subscribes to bus.
+// Sinks send errors in the background, the returned flush waits until they are sent and should be called before
+// the process exits
+func createErrorHandler(config *hocon.Config, handler core.ErrorHandler, bus *core.Bus, exit func(err error), logger *logging.Logger) (core.ErrorHandler, func(), error) {
+	eCfg := config.GetConfig("errors")
+	if eCfg == nil {
+		return handler, func() {}, nil
+	}
+
+	var sinks []core.ErrorHandler
+	var closers []io.Closer
+	if wCfg := eCfg.GetConfig("webhook"); wCfg != nil && wCfg.GetBoolean("enabled") {
+		w, err := createWebhook(wCfg, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create error webhook: %w", err)
+		}
+		sinks = append(sinks, w)
+		closers = append(closers, w)
+	}
+	var dedup time.Duration
+	var err error
+	if dCfg := eCfg.GetConfig("dedup"); dCfg != nil && dCfg.GetBoolean("enabled") {
+		if len(sinks) == 0 {
+			return nil, nil, errors.New("dedup collapses errors passed to sinks, but none is enabled, e.g. errors.webhook")
+		}
+		dedup, err = parseDuration(dCfg, "window")
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse dedup window: %w", err)
+		}
+		if dedup <= 0 {
+			return nil, nil, fmt.Errorf("dedup window must be positive, actual: %v", dedup)
+		}
+	}
+	if len(sinks) > 0 {
+		var sink core.ErrorHandler = errHandlers.FanOut(sinks...)
+		if dedup > 0 {
+			sink = errHandlers.Dedup(sink, dedup)
+		}
+		handler = errHandlers.FanOut(handler, sink)
+	}
+
+	if esCfg := eCfg.GetConfig("escalate"); esCfg != nil && esCfg.GetBoolean("enabled") {
+		after := esCfg.GetInt("after")
+		if after < 1 {
+			return nil, nil, errors.New("number of consecutive errors escalated to exit must be positive")
+		}
+		bus.Subscribe(errHandlers.Escalate(after, exit))
+	}
+	flush := func() {
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				logger.Error("could not flush errors", "err", err)
+			}
+		}
+	}
+	return handler, flush, nil
+}
+
+// countErrors tells if errors should be counted with Prometheus metrics
+func countErrors(config *hocon.Config, promCfg prometheusCfg) bool {
+	return promCfg.enabled && (promCfg.errorsEnabled || config.GetBoolean("errors.metrics"))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/health"
+	"time"
+)
+
+type healthCfg struct {
+	enabled bool
+	cfg     health.Cfg
+}
+
+// parseHealthCfg parses optional health block. Zero age disables the check of the age
+func parseHealthCfg(config *hocon.Config) (healthCfg, error) {
+	cfg := config.GetConfig("health")
+	if cfg == nil || !cfg.GetBoolean("enabled") {
+		return healthCfg{enabled: false}, nil
+	}
+
+	hCfg := health.Cfg{}
+	for path, d := range map[string]*time.Duration{
+		"max-test-age": &hCfg.MaxTestAge,
+		"max-push-age": &hCfg.MaxPushAge,
+		"ping-timeout": &hCfg.PingTimeout,
+	} {
+		if cfg.Get(path) == nil {
+			continue
+		}
+		parsed, err := parseDuration(cfg, path)
+		if err != nil {
+			return healthCfg{}, err
+		}
+		*d = parsed
+	}
+	return healthCfg{enabled: true, cfg: hCfg}, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"log"
+)
+
+// parseLoggingCfg parses optional logging block. Missing block means logfmt at info level
+func parseLoggingCfg(config *hocon.Config) (logging.Cfg, error) {
+	cfg := config.GetConfig("logging")
+	if cfg == nil {
+		return logging.Cfg{}, nil
+	}
+
+	level, err := logging.ParseLevel(cfg.GetString("level"))
+	if err != nil {
+		return logging.Cfg{}, err
+	}
+	packages := make(map[string]logging.Level)
+	for name, l := range cfg.GetStringMapString("packages") {
+		packages[name], err = logging.ParseLevel(l)
+		if err != nil {
+			return logging.Cfg{}, fmt.Errorf("invalid log level of package: %s: %w", name, err)
+		}
+	}
+	return logging.Cfg{Level: level, Format: logging.Format(cfg.GetString("format")), Packages: packages}, nil
+}
+
+// createLogger creates logger configured with logging block and makes it default for all packages and for the
+// standard logger used by libraries
+func createLogger(config *hocon.Config) (*logging.Logger, error) {
+	lCfg, err := parseLoggingCfg(config)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := logging.New(lCfg)
+	if err != nil {
+		return nil, err
+	}
+	logging.SetDefault(logger)
+	log.SetFlags(0)
+	log.SetOutput(logger.Named("stdlib").Writer(logging.Info))
+	return logger, nil
+}
@@ -2,67 +2,284 @@ package main
 
 import (
 	"context"
-	"github.com/gurkankaymak/hocon"
+	"flag"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/email"
 	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"github.com/paluszkiewiczB/speedtest/internal/health"
 	"github.com/paluszkiewiczB/speedtest/internal/influx"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
 	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"github.com/paluszkiewiczB/speedtest/internal/outage"
 	"github.com/paluszkiewiczB/speedtest/internal/schedule"
-	"log"
+	"github.com/paluszkiewiczB/speedtest/internal/ui"
 	"os"
-	"os/signal"
+	"strings"
 )
 
 func main() {
-	cfg, err := hocon.ParseResource("reference.conf")
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && isHelp(args[0]) {
+		command = "help"
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		run(args)
+	case "once":
+		once(args)
+	case "report":
+		report(args)
+	case "serve":
+		serve(args)
+	case "config":
+		configCommand(args)
+	case "version":
+		printVersion()
+	case "help":
+		printUsage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", command)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+}
+
+// run runs speed tests on schedule until SIGINT or SIGTERM
+func run(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	source := addConfigFlags(flags)
+	_ = flags.Parse(args)
+
+	cfg := source.mustLoad()
+	logger, err := createLogger(cfg)
 	if err != nil {
-		log.Fatalf("could not parse config: %v\n", err)
-		return
+		logging.Default().Fatal("could not create logger", "err", err)
 	}
 	stc, err := parseSpeedTestCfg(cfg.GetConfig("speedtest"))
 	if err != nil {
-		log.Fatalf("could not parse speed test cfg: %v\n", err)
+		logger.Fatal("could not parse speed test cfg", "err", err)
 	}
 
+	bus := core.NewBus()
+	stc.publishTo(bus)
+	stc.logTo(logger)
+
 	tester, err := createSpeedTester(stc.clientCfg)
 	if err != nil {
-		log.Fatalf("could not create speed tester: %v", err)
+		logger.Fatal("could not create speed tester", "err", err)
 	}
 
-	storage, err := createStorage(cfg.GetConfig("storage"))
+	storage, err := createStorage(cfg.GetConfig("storage"), logger)
 	if err != nil {
-		log.Fatalf("could not create storage: %v\n", err)
+		logger.Fatal("could not create storage", "err", err)
 	}
+	// decorators of the storage do not ping, so health checks and the dashboard use the storage itself
+	pinger, _ := storage.(health.Pinger)
+	history, _ := storage.(core.Queryable)
 
-	scheduler := schedule.NewScheduler()
+	scheduler := schedule.NewScheduler(schedule.WithLogger(logger))
+
+	shutdownTracing, err := setupTracing(context.Background(), cfg)
+	if err != nil {
+		logger.Fatal("could not set up tracing", "err", err)
+	}
+	stopMetrics, err := exportOTLPMetrics(context.Background(), cfg, bus)
+	if err != nil {
+		logger.Fatal("could not export OTLP metrics", "err", err)
+	}
 
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	shutdownC := make(chan os.Signal, 1)
-	signal.Notify(shutdownC, os.Interrupt)
-	go func() {
-		<-shutdownC
-		cancelFunc()
-	}()
+	ctx, cancel := context.WithCancel(signalContext(logger))
+	defer cancel()
+	// escalation shuts down like a signal, so pending results are pushed before the process exits
+	escalated := make(chan error, 1)
+	escalate := func(err error) {
+		select {
+		case escalated <- err:
+			logger.Error("too many consecutive errors, shutting down", "err", err)
+			cancel()
+		default:
+		}
+	}
 
+	var handler core.ErrorHandler = errHandlers.NewStructured(logger)
+	handler, flushErrors, err := createErrorHandler(cfg, handler, bus, escalate, logger)
+	if err != nil {
+		logger.Fatal("could not create error handler", "err", err)
+	}
+	srv, err := createServer(cfg, logger)
+	if err != nil {
+		logger.Fatal("could not create http server", "err", err)
+	}
 	promCfg := parsePrometheusCfg(cfg)
+	var metrics *observe.Metrics
 	if promCfg.enabled {
-		observe.ExposePrometheus(ctx, promCfg.cfg)
+		if srv == nil {
+			logger.Fatal("prometheus endpoint needs enabled server")
+		}
+		registry := observe.NewRegistry()
+		metrics = observe.NewMetrics(registry)
+		srv.Handle(promCfg.endpoint, observe.Handler(registry))
 		if promCfg.storageEnabled {
-			storage = observe.Storage(storage)
+			storage = metrics.Storage(storage)
+		}
+		if countErrors(cfg, promCfg) {
+			handler = metrics.ErrorHandler(handler)
 		}
 	}
 
 	if i, ok := storage.(influx.Client); ok {
 		err = i.Ping(ctx)
 		if err != nil {
-			log.Fatal(err)
+			logger.Fatal("could not connect to storage", "err", err)
+		}
+	}
+
+	oCfg, err := parseOutageCfg(cfg)
+	if err != nil {
+		logger.Fatal("could not parse outage cfg", "err", err)
+	}
+	bootStorage := storage
+	if oCfg.enabled {
+		outageStorage, ok := storage.(core.OutageStorage)
+		if !ok {
+			logger.Fatal("storage does not support outages", "storage", fmt.Sprintf("%T", storage))
+		}
+		oCfg.cfg.Logger = logger
+		monitor := outage.NewMonitor(oCfg.cfg, outageStorage, handler)
+		err = scheduler.Schedule(ctx, "OutageMonitor", oCfg.interval, func() {
+			monitor.Check(ctx)
+		})
+		if err != nil {
+			logger.Fatal("could not schedule outage monitor", "err", err)
 		}
+		bootStorage = monitor.StopBefore(storage)
 	}
 
-	handler := errHandlers.NewPrintln()
-	bootCfg := core.Config{SpeedTestInterval: stc.schedulerCfg.duration}
-	err = core.Boot(ctx, bootCfg, scheduler, tester, storage, handler)
+	jobs, err := createJobs(stc, logger)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("could not create jobs", "err", err)
+	}
+	for i, job := range jobs {
+		if job.Storage == nil {
+			continue
+		}
+		if c, ok := job.Storage.(influx.Client); ok {
+			err = c.Ping(ctx)
+			if err != nil {
+				logger.Fatal("could not connect to storage of job", "job", job.Name, "err", err)
+			}
+		}
+		if promCfg.enabled && promCfg.storageEnabled {
+			jobs[i].Storage = metrics.Storage(job.Storage)
+		}
+	}
+
+	aCfg, err := parseAlertsCfg(cfg)
+	if err != nil {
+		logger.Fatal("could not parse alerts cfg", "err", err)
+	}
+	if aCfg.enabled {
+		notifiers, err := createNotifiers(cfg, aCfg.notifiers, logger)
+		if err != nil {
+			logger.Fatal("could not create alert notifiers", "err", err)
+		}
+		aCfg.cfg.Logger = logger
+		engine := alert.NewEngine(aCfg.cfg, notifiers, handler)
+		bus.Subscribe(engine)
+		go engine.Run(ctx)
+	}
+
+	dCfg, err := parseDigestCfg(cfg)
+	if err != nil {
+		logger.Fatal("could not parse digest cfg", "err", err)
+	}
+	if dCfg.enabled {
+		queryable, ok := storage.(core.Queryable)
+		if !ok {
+			logger.Fatal("storage does not support queries", "storage", fmt.Sprintf("%T", storage))
+		}
+		mailer, err := createMailer(cfg)
+		if err != nil {
+			logger.Fatal("could not create mailer", "err", err)
+		}
+		digest, err := email.NewDigest(dCfg.cfg, queryable, mailer)
+		if err != nil {
+			logger.Fatal("could not create digest", "err", err)
+		}
+		go digest.Run(ctx, handler)
+	}
+
+	gracePeriod, err := parseGracePeriod(cfg)
+	if err != nil {
+		logger.Fatal("could not parse shutdown cfg", "err", err)
+	}
+
+	pushTimeout, err := parsePushTimeout(cfg)
+	if err != nil {
+		logger.Fatal("could not parse storage timeout", "err", err)
+	}
+
+	bootCfg := core.Config{
+		SpeedTestInterval: stc.schedulerCfg.duration,
+		Jobs:              jobs,
+		GracePeriod:       gracePeriod,
+		PushTimeout:       pushTimeout,
+		Queue:             stc.queueCfg,
+		Bus:               bus,
+		Logger:            logger,
+	}
+	if promCfg.enabled && promCfg.queueEnabled {
+		bootCfg.QueueObserver = metrics.QueueObserver()
+	}
+	hCfg, err := parseHealthCfg(cfg)
+	if err != nil {
+		logger.Fatal("could not parse health cfg", "err", err)
+	}
+	if hCfg.enabled {
+		if srv == nil {
+			logger.Fatal("health endpoints need enabled server")
+		}
+		checker := health.NewChecker(hCfg.cfg, scheduler, pinger)
+		bus.Subscribe(checker)
+		srv.HandlePublic("/healthz", health.Handler(func(context.Context) health.Report { return checker.Liveness() }))
+		srv.HandlePublic("/readyz", health.Handler(checker.Readiness))
+	}
+
+	if cfg.GetBoolean("ui.enabled") {
+		if srv == nil {
+			logger.Fatal("ui needs enabled server")
+		}
+		dashboard := ui.New(ui.Cfg{Storage: history, Logger: logger}, scheduler)
+		bus.Subscribe(dashboard)
+		srv.Handle("/", dashboard.Handler())
+	}
+
+	if srv != nil {
+		go func() {
+			if err := srv.Run(ctx); err != nil {
+				logger.Error("http server failed", "err", err)
+			}
+		}()
+	}
+	err = core.Boot(ctx, bootCfg, scheduler, tester, bootStorage, handler)
+	flushErrors()
+	if tErr := shutdownTracing(context.Background()); tErr != nil {
+		logger.Error("could not flush spans", "err", tErr)
+	}
+	if mErr := stopMetrics(context.Background()); mErr != nil {
+		logger.Error("could not push the last OTLP metrics", "err", mErr)
+	}
+	if err != nil {
+		logger.Fatal("speed test failed", "err", err)
+	}
+	select {
+	case err := <-escalated:
+		logger.Fatal("exiting after too many consecutive errors", "err", err)
+	default:
 	}
 }
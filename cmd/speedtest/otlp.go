@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/observe"
+)
+
+type otlpMetricsCfg struct {
+	enabled bool
+	cfg     observe.OTLPConfig
+}
+
+// parseOTLPMetricsCfg parses optional otlp-metrics block. It is independent of prometheus block, so metrics can be
+// scraped, pushed or both
+func parseOTLPMetricsCfg(config *hocon.Config) (otlpMetricsCfg, error) {
+	cfg := config.GetConfig("otlp-metrics")
+	if cfg == nil || !cfg.GetBoolean("enabled") {
+		return otlpMetricsCfg{enabled: false}, nil
+	}
+	protocol := observe.OTLPProtocol(cfg.GetString("protocol"))
+	if protocol != observe.OTLPHTTP && protocol != observe.OTLPGRPC {
+		return otlpMetricsCfg{}, fmt.Errorf("unsupported OTLP protocol: %s", protocol)
+	}
+	oCfg := observe.OTLPConfig{
+		Protocol:    protocol,
+		Endpoint:    cfg.GetString("endpoint"),
+		Insecure:    cfg.GetBoolean("insecure"),
+		Headers:     cfg.GetStringMapString("headers"),
+		ServiceName: cfg.GetString("service-name"),
+	}
+	if cfg.Get("interval") != nil {
+		interval, err := parseDuration(cfg, "interval")
+		if err != nil {
+			return otlpMetricsCfg{}, err
+		}
+		oCfg.Interval = interval
+	}
+	return otlpMetricsCfg{enabled: true, cfg: oCfg}, nil
+}
+
+// exportOTLPMetrics pushes metrics of events published on the bus to OTLP collector. Returned function pushes
+// the last metrics, it does nothing when export is disabled
+func exportOTLPMetrics(ctx context.Context, config *hocon.Config, bus *core.Bus) (func(context.Context) error, error) {
+	oCfg, err := parseOTLPMetricsCfg(config)
+	if err != nil {
+		return nil, err
+	}
+	if !oCfg.enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	c, err := observe.ExportOTLP(ctx, oCfg.cfg)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := observe.NewOTelMetrics(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not create OpenTelemetry instruments: %w", err)
+	}
+	bus.Subscribe(metrics)
+	return c.Stop, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/analytics"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportCfg are the defaults of the report subcommand, which can be overridden with its flags
+type reportCfg struct {
+	interval               time.Duration
+	minDownload, minUpload float64
+	maxPing                time.Duration
+	// tags select results of the job. Results of all jobs are reported without them
+	tags map[string]string
+}
+
+// parseReportCfg parses optional report block. Interval is the duration of the scheduler of the job, or of
+// speedtest.scheduler when job is empty
+func parseReportCfg(config *hocon.Config, job string) (reportCfg, error) {
+	sCfg, err := parseSchedulerCfg(config.GetConfig("speedtest.scheduler"))
+	if err != nil {
+		return reportCfg{}, fmt.Errorf("could not parse scheduler cfg: %w", err)
+	}
+	rCfg := reportCfg{interval: sCfg.duration}
+	if job != "" {
+		rCfg.interval, rCfg.tags, err = parseReportJob(config.GetConfig("speedtest"), job)
+		if err != nil {
+			return reportCfg{}, err
+		}
+	}
+	cfg := config.GetConfig("report")
+	if cfg == nil {
+		return rCfg, nil
+	}
+	rCfg.minDownload = parseFloat(cfg, "min-download")
+	rCfg.minUpload = parseFloat(cfg, "min-upload")
+	if cfg.Get("max-ping") != nil {
+		rCfg.maxPing, err = parseDuration(cfg, "max-ping")
+		if err != nil {
+			return reportCfg{}, err
+		}
+	}
+	return rCfg, nil
+}
+
+// parseReportJob finds the job or the interface with the name and returns its interval and tags of its results
+func parseReportJob(cfg *hocon.Config, name string) (time.Duration, map[string]string, error) {
+	jobs, err := parseJobs(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, j := range jobs {
+		if j.name != name {
+			continue
+		}
+		if len(j.tags) == 0 {
+			return 0, nil, fmt.Errorf("job: %s has no tags, so its results can not be told apart from other jobs", name)
+		}
+		return j.schedulerCfg.duration, j.tags, nil
+	}
+
+	interfaces, err := parseInterfaces(cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, i := range interfaces {
+		if i.name == name {
+			sCfg, err := parseSchedulerCfg(cfg.GetConfig("scheduler"))
+			if err != nil {
+				return 0, nil, fmt.Errorf("could not parse scheduler cfg: %w", err)
+			}
+			return sCfg.duration, map[string]string{"interface": i.name}, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("unknown job: %s", name)
+}
+
+// withTags returns results having all the tags
+func withTags(speeds []core.Speed, tags map[string]string) []core.Speed {
+	out := make([]core.Speed, 0, len(speeds))
+	for _, s := range speeds {
+		matches := true
+		for k, v := range tags {
+			if s.Tags[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// report prints statistics and SLA of results from the storage. Flags of the SLA override the report block
+func report(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	source := addConfigFlags(flags)
+	from := flags.String("from", "", "start of the report, RFC 3339 time or date, e.g. 2022-10-01. Defaults to -last before -to")
+	to := flags.String("to", "", "end of the report (exclusive), RFC 3339 time or date. Defaults to now")
+	last := flags.Duration("last", 7*24*time.Hour, "length of the report, when -from is not set")
+	format := flags.String("format", string(analytics.Markdown), "format of the report: markdown, csv or json")
+	output := flags.String("output", "", "file the report is written to. Defaults to stdout")
+	job := flags.String("job", "", "name of the job or the interface, whose results are reported. Defaults to results of all jobs")
+	interval := flags.Duration("interval", 0, "expected time between speed tests, longer gaps are unavailable. Defaults to the scheduler duration of the job or speedtest.scheduler.duration")
+	percentiles := flags.String("percentiles", "5,50,95", "comma separated percentiles of every metric")
+	minDownload := flags.Float64("min-download", 0, "minimal download speed of the SLA in Mbps, 0 disables it. Defaults to report.min-download")
+	minUpload := flags.Float64("min-upload", 0, "minimal upload speed of the SLA in Mbps, 0 disables it. Defaults to report.min-upload")
+	maxPing := flags.Duration("max-ping", 0, "maximal ping of the SLA, 0 disables it. Defaults to report.max-ping")
+	_ = flags.Parse(args)
+
+	config := source.mustLoad()
+	logger, err := createLogger(config)
+	if err != nil {
+		logging.Default().Fatal("could not create logger", "err", err)
+	}
+	rCfg, err := parseReportCfg(config, *job)
+	if err != nil {
+		logger.Fatal("could not parse report cfg", "err", err)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "interval":
+			rCfg.interval = *interval
+		case "min-download":
+			rCfg.minDownload = *minDownload
+		case "min-upload":
+			rCfg.minUpload = *minUpload
+		case "max-ping":
+			rCfg.maxPing = *maxPing
+		}
+	})
+
+	f, err := analytics.ParseFormat(*format)
+	if err != nil {
+		logger.Fatal("invalid format of the report", "err", err)
+	}
+	window, err := parseWindow(*from, *to, *last, time.Now())
+	if err != nil {
+		logger.Fatal("invalid window of the report", "err", err)
+	}
+	aCfg := analytics.Cfg{Interval: rCfg.interval}
+	aCfg.Percentiles, err = parsePercentiles(*percentiles)
+	if err != nil {
+		logger.Fatal("invalid percentiles", "err", err)
+	}
+	if rCfg.minDownload > 0 {
+		aCfg.Thresholds = append(aCfg.Thresholds, analytics.Threshold{Metric: analytics.Download, Value: rCfg.minDownload})
+	}
+	if rCfg.minUpload > 0 {
+		aCfg.Thresholds = append(aCfg.Thresholds, analytics.Threshold{Metric: analytics.Upload, Value: rCfg.minUpload})
+	}
+	if rCfg.maxPing > 0 {
+		aCfg.Thresholds = append(aCfg.Thresholds, analytics.Threshold{Metric: analytics.Ping, Value: float64(rCfg.maxPing) / float64(time.Millisecond)})
+	}
+
+	storage, err := createStorage(config.GetConfig("storage"), logger)
+	if err != nil {
+		logger.Fatal("could not create storage", "err", err)
+	}
+	defer storage.Close()
+	queryable, ok := storage.(core.Queryable)
+	if !ok {
+		logger.Fatal("storage does not support queries", "storage", fmt.Sprintf("%T", storage))
+	}
+	speeds, err := queryable.Query(context.Background(), window.From, window.To)
+	if err != nil {
+		logger.Fatal("could not query speeds", "err", err)
+	}
+	r, err := analytics.Compute(withTags(speeds, rCfg.tags), window, aCfg)
+	if err != nil {
+		logger.Fatal("could not compute report", "err", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			logger.Fatal("could not create report file", "err", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := analytics.Write(w, r, f); err != nil {
+		logger.Fatal("could not write report", "err", err)
+	}
+}
+
+// parseWindow parses bounds of the report. Missing end is now and missing start is last before the end
+func parseWindow(from, to string, last time.Duration, now time.Time) (analytics.Window, error) {
+	window := analytics.Window{To: now}
+	var err error
+	if to != "" {
+		if window.To, err = parseTime(to); err != nil {
+			return analytics.Window{}, err
+		}
+	}
+	window.From = window.To.Add(-last)
+	if from != "" {
+		if window.From, err = parseTime(from); err != nil {
+			return analytics.Window{}, err
+		}
+	}
+	return window, nil
+}
+
+// parseTime accepts RFC 3339 time or date, which is midnight of the local time zone
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time must be RFC 3339 or date, actual: %s", s)
+	}
+	return t, nil
+}
+
+func parsePercentiles(s string) ([]float64, error) {
+	percentiles := make([]float64, 0)
+	for _, p := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile: %s: %w", p, err)
+		}
+		percentiles = append(percentiles, v)
+	}
+	return percentiles, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"github.com/paluszkiewiczB/speedtest/internal/httpspeed"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+)
+
+// serve runs speed test server, so other instance can measure LAN throughput with client type LAN
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	downloadSize := flags.Int64("download-size", 25*1024*1024, "default number of bytes sent by download endpoint")
+	_ = flags.Parse(args)
+
+	ctx := signalContext(logging.Default())
+	err := httpspeed.Serve(ctx, httpspeed.ServerCfg{Addr: *addr, DownloadSize: *downloadSize})
+	if err != nil {
+		logging.Default().Fatal("could not serve speed test", "err", err)
+	}
+}
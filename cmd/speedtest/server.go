@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/server"
+)
+
+// parseServerCfg parses server block shared by metrics and other HTTP endpoints. Nil is returned when the server
+// is disabled
+func parseServerCfg(config *hocon.Config) (*server.Cfg, error) {
+	cfg := config.GetConfig("server")
+	if cfg == nil || !cfg.GetBoolean("enabled") {
+		return nil, nil
+	}
+
+	sCfg := &server.Cfg{Port: cfg.GetInt("port")}
+	if tCfg := cfg.GetConfig("tls"); tCfg != nil && tCfg.GetBoolean("enabled") {
+		cert, err := tls.LoadX509KeyPair(tCfg.GetString("cert-file"), tCfg.GetString("key-file"))
+		if err != nil {
+			return nil, fmt.Errorf("could not load certificate of the server: %w", err)
+		}
+		sCfg.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+	if aCfg := cfg.GetConfig("basic-auth"); aCfg != nil && aCfg.GetBoolean("enabled") {
+		auth := server.BasicAuth{Username: aCfg.GetString("username"), Password: aCfg.GetString("password")}
+		if auth.Username == "" || auth.Password == "" {
+			return nil, errors.New("basic auth of the server needs username and password")
+		}
+		sCfg.BasicAuth = &auth
+	}
+	return sCfg, nil
+}
+
+func createServer(config *hocon.Config, logger *logging.Logger) (*server.Server, error) {
+	sCfg, err := parseServerCfg(config)
+	if err != nil || sCfg == nil {
+		return nil, err
+	}
+	sCfg.Logger = logger
+	return server.New(*sCfg), nil
+}
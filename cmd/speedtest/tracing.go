@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+)
+
+type tracingCfg struct {
+	enabled bool
+	cfg     tracing.Cfg
+}
+
+// parseTracingCfg parses optional tracing block. Tracing is disabled when the block is missing
+func parseTracingCfg(config *hocon.Config) (tracingCfg, error) {
+	cfg := config.GetConfig("tracing")
+	if cfg == nil || !cfg.GetBoolean("enabled") {
+		return tracingCfg{enabled: false}, nil
+	}
+	exporter := tracing.Exporter(cfg.GetString("exporter"))
+	switch exporter {
+	case tracing.Stdout, tracing.OTLPHTTP, tracing.OTLPGRPC:
+	default:
+		return tracingCfg{}, fmt.Errorf("unsupported tracing exporter: %s", exporter)
+	}
+	return tracingCfg{enabled: true, cfg: tracing.Cfg{
+		Exporter:    exporter,
+		Endpoint:    cfg.GetString("endpoint"),
+		Insecure:    cfg.GetBoolean("insecure"),
+		Headers:     cfg.GetStringMapString("headers"),
+		ServiceName: cfg.GetString("service-name"),
+		SampleRatio: parseFloat(cfg, "sample-ratio"),
+	}}, nil
+}
+
+// setupTracing registers tracer provider configured with tracing block. Returned function flushes pending spans,
+// it does nothing when tracing is disabled
+func setupTracing(ctx context.Context, config *hocon.Config) (func(context.Context) error, error) {
+	tCfg, err := parseTracingCfg(config)
+	if err != nil {
+		return nil, err
+	}
+	if !tCfg.enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	return tracing.Setup(ctx, tCfg.cfg)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"github.com/gurkankaymak/hocon"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/webhook"
+	"time"
+)
+
+// parseWebhookCfg parses configuration of the webhook shared by storage, alert notifier and error handler
+func parseWebhookCfg(cfg *hocon.Config) (webhook.Cfg, error) {
+	wCfg := webhook.Cfg{
+		Url:             cfg.GetString("url"),
+		Method:          cfg.GetString("method"),
+		Headers:         cfg.GetStringMapString("headers"),
+		Secret:          cfg.GetString("secret"),
+		SignatureHeader: cfg.GetString("signature-header"),
+	}
+
+	if templates := cfg.GetConfig("templates"); templates != nil {
+		wCfg.Templates = webhook.Templates{
+			Result: templates.GetString("result"),
+			Alert:  templates.GetString("alert"),
+			Error:  templates.GetString("error"),
+		}
+	}
+
+	if cfg.Get("timeout") != nil {
+		timeout, err := parseDuration(cfg, "timeout")
+		if err != nil {
+			return webhook.Cfg{}, err
+		}
+		wCfg.Timeout = timeout
+	}
+
+	if retry := cfg.GetConfig("retry"); retry != nil {
+		interval := time.Duration(0)
+		if retry.Get("interval") != nil {
+			d, err := parseDuration(retry, "interval")
+			if err != nil {
+				return webhook.Cfg{}, err
+			}
+			interval = d
+		}
+		wCfg.Retry = webhook.RetryCfg{Times: retry.GetInt("tries"), Wait: interval}
+	}
+	return wCfg, nil
+}
+
+func createWebhook(cfg *hocon.Config, logger *logging.Logger) (*webhook.Webhook, error) {
+	if cfg == nil {
+		return nil, errors.New("missing webhook configuration")
+	}
+	wCfg, err := parseWebhookCfg(cfg)
+	if err != nil {
+		return nil, err
+	}
+	wCfg.Logger = logger
+	return webhook.New(wCfg)
+}
@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"sync"
+	"time"
+)
+
+// State of the alert
+type State string
+
+const (
+	Firing   State = "firing"
+	Resolved State = "resolved"
+)
+
+// Alert is sent to notifiers when the rule starts firing for the job, when it is resolved and, if configured,
+// repeatedly while it keeps firing
+type Alert struct {
+	Rule        string
+	Job         string
+	State       State
+	Description string
+	// Value is the evaluated value of the metric which caused the change of the state
+	Value    float64
+	StartsAt time.Time
+	// EndsAt is zero, unless the alert is resolved
+	EndsAt time.Time
+	// Tags of the latest result
+	Tags map[string]string
+}
+
+// Notifier delivers alerts, e.g. to a chat or an e-mail
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+type Cfg struct {
+	Rules []Rule
+	// RepeatInterval is how often firing alert is sent again. Zero means it is sent only once
+	RepeatInterval time.Duration
+	// Buffer is the number of alerts waiting for notifiers. When it is full, alerts are dropped
+	Buffer int
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+func NewEngine(cfg Cfg, notifiers []Notifier, errH core.ErrorHandler) *Engine {
+	count, window := 1, time.Duration(0)
+	for _, r := range cfg.Rules {
+		c, w := r.Retention()
+		if c > count {
+			count = c
+		}
+		if w > window {
+			window = w
+		}
+	}
+	buffer := cfg.Buffer
+	if buffer < 1 {
+		buffer = 64
+	}
+	return &Engine{
+		cfg:       cfg,
+		notifiers: notifiers,
+		errH:      errH,
+		count:     count,
+		window:    window,
+		history:   make(map[string][]core.Speed),
+		active:    make(map[key]*Alert),
+		lastSent:  make(map[key]time.Time),
+		pending:   make(chan Alert, buffer),
+		logger:    logging.For(cfg.Logger, "alert"),
+	}
+}
+
+// Engine evaluates rules for results of every job published on core.Bus and sends alerts when the state of the
+// rule changes. Alerts are delivered by Run, so slow notifiers do not block the bus
+type Engine struct {
+	cfg       Cfg
+	notifiers []Notifier
+	errH      core.ErrorHandler
+	// count and window is retention of results required by all rules
+	count  int
+	window time.Duration
+
+	mu      sync.Mutex
+	history map[string][]core.Speed
+	active  map[key]*Alert
+	// lastSent is when the firing alert was sent for the last time
+	lastSent map[key]time.Time
+	pending  chan Alert
+	logger   *logging.Logger
+}
+
+// key identifies the alert, so the same rule firing for the same job is sent once
+type key struct {
+	rule, job string
+}
+
+func (e *Engine) Notify(event core.Event) {
+	if r, ok := event.(core.ResultProduced); ok {
+		e.Evaluate(r.Job, r.Speed)
+	}
+}
+
+// Evaluate adds the result to the history of the job and evaluates all rules
+func (e *Engine) Evaluate(job string, speed core.Speed) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	results := e.retain(append(e.history[job], speed))
+	e.history[job] = results
+
+	for _, r := range e.cfg.Rules {
+		eval, err := r.Evaluate(results)
+		if err != nil {
+			e.errH.Handle(fmt.Errorf("could not evaluate alert rule: %s: %w", r.Name(), err))
+			continue
+		}
+		e.transition(key{rule: r.Name(), job: job}, eval, speed)
+	}
+}
+
+// transition sends the alert when it starts firing, is resolved or should be repeated
+func (e *Engine) transition(k key, eval Evaluation, speed core.Speed) {
+	active, firing := e.active[k]
+	switch {
+	case eval.Firing && !firing:
+		a := &Alert{
+			Rule:        k.rule,
+			Job:         k.job,
+			State:       Firing,
+			Description: eval.Description,
+			Value:       eval.Value,
+			StartsAt:    speed.Timestamp,
+			Tags:        speed.Tags,
+		}
+		e.active[k] = a
+		e.send(k, *a, speed.Timestamp)
+	case eval.Firing && firing:
+		if e.cfg.RepeatInterval > 0 && speed.Timestamp.Sub(e.lastSent[k]) >= e.cfg.RepeatInterval {
+			active.Value = eval.Value
+			active.Tags = speed.Tags
+			e.send(k, *active, speed.Timestamp)
+		}
+	case !eval.Firing && firing:
+		delete(e.active, k)
+		delete(e.lastSent, k)
+		resolved := *active
+		resolved.State = Resolved
+		resolved.Value = eval.Value
+		resolved.EndsAt = speed.Timestamp
+		resolved.Tags = speed.Tags
+		e.enqueue(resolved)
+	}
+}
+
+func (e *Engine) send(k key, a Alert, at time.Time) {
+	e.lastSent[k] = at
+	e.enqueue(a)
+}
+
+func (e *Engine) enqueue(a Alert) {
+	select {
+	case e.pending <- a:
+	default:
+		e.logger.Warn("too many pending alerts, dropping alert", "rule", a.Rule, "job", a.Job, "state", a.State)
+	}
+}
+
+// retain drops results which are not needed by any rule. Results are ordered from the oldest
+func (e *Engine) retain(results []core.Speed) []core.Speed {
+	latest := results[len(results)-1].Timestamp
+	first := 0
+	for first < len(results)-e.count && results[first].Timestamp.Before(latest.Add(-e.window)) {
+		first++
+	}
+	return results[first:]
+}
+
+// Active returns alerts which are firing
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := make([]Alert, 0, len(e.active))
+	for _, a := range e.active {
+		alerts = append(alerts, *a)
+	}
+	return alerts
+}
+
+// Run delivers alerts to all notifiers until ctx is cancelled. Errors of notifiers are passed to the error handler
+func (e *Engine) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-e.pending:
+			for _, n := range e.notifiers {
+				err := n.Notify(ctx, a)
+				if err != nil {
+					e.errH.Handle(fmt.Errorf("could not send %s alert: %s: %w", a.State, a.Rule, err))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package alert_test
+
+import (
+	"context"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"sync"
+	"testing"
+	"time"
+)
+
+var slowDownload = alert.Consecutive{RuleName: "slow", Metric: alert.Download, Condition: alert.Below, Threshold: 300, Count: 2}
+
+func TestEngine_FiringAndResolved(t *testing.T) {
+	notifier := &recordingNotifier{}
+	engine := alert.NewEngine(alert.Cfg{Rules: []alert.Rule{slowDownload}}, []alert.Notifier{notifier}, &countingHandler{})
+
+	for i, d := range []float64{100, 100, 100, 100, 400, 400} {
+		engine.Notify(core.ResultProduced{Job: "home", Speed: speed(i, d)})
+	}
+	deliver(t, engine, notifier, 2)
+
+	alerts := notifier.get()
+	if alerts[0].State != alert.Firing || alerts[0].StartsAt != time.Unix(1, 0) {
+		t.Fatalf("expected alert firing since the second result, actual: %+v", alerts[0])
+	}
+	if alerts[1].State != alert.Resolved || alerts[1].EndsAt != time.Unix(4, 0) || alerts[1].Value != 400 {
+		t.Fatalf("expected alert resolved by the fifth result, actual: %+v", alerts[1])
+	}
+	if len(engine.Active()) != 0 {
+		t.Fatalf("expected no active alerts, actual: %v", engine.Active())
+	}
+}
+
+func TestEngine_Repeat(t *testing.T) {
+	notifier := &recordingNotifier{}
+	cfg := alert.Cfg{Rules: []alert.Rule{slowDownload}, RepeatInterval: 2 * time.Second}
+	engine := alert.NewEngine(cfg, []alert.Notifier{notifier}, &countingHandler{})
+
+	for i := 0; i < 6; i++ {
+		engine.Notify(core.ResultProduced{Job: "home", Speed: speed(i, 100)})
+	}
+	deliver(t, engine, notifier, 3)
+
+	for _, a := range notifier.get() {
+		if a.State != alert.Firing || a.StartsAt != time.Unix(1, 0) {
+			t.Fatalf("expected repeated firing alert, actual: %+v", a)
+		}
+	}
+}
+
+func TestEngine_PerJob(t *testing.T) {
+	notifier := &recordingNotifier{}
+	engine := alert.NewEngine(alert.Cfg{Rules: []alert.Rule{slowDownload}}, []alert.Notifier{notifier}, &countingHandler{})
+
+	engine.Notify(core.ResultProduced{Job: "fiber", Speed: speed(0, 100)})
+	engine.Notify(core.ResultProduced{Job: "lte", Speed: speed(1, 100)})
+	engine.Notify(core.ResultProduced{Job: "fiber", Speed: speed(2, 400)})
+	engine.Notify(core.ResultProduced{Job: "lte", Speed: speed(3, 100)})
+
+	active := engine.Active()
+	if len(active) != 1 || active[0].Job != "lte" {
+		t.Fatalf("expected alert only for lte, actual: %+v", active)
+	}
+}
+
+func TestEngine_NotifierError(t *testing.T) {
+	failing := alert.NotifierFunc(func(context.Context, alert.Alert) error {
+		return errors.New("notifier failed")
+	})
+	notifier := &recordingNotifier{}
+	handler := &countingHandler{}
+	engine := alert.NewEngine(alert.Cfg{Rules: []alert.Rule{slowDownload}}, []alert.Notifier{failing, notifier}, handler)
+
+	engine.Notify(core.ResultProduced{Job: "home", Speed: speed(0, 100)})
+	engine.Notify(core.ResultProduced{Job: "home", Speed: speed(1, 100)})
+	deliver(t, engine, notifier, 1)
+
+	if handler.get() != 1 {
+		t.Fatalf("expected 1 error handled, actual: %d", handler.get())
+	}
+}
+
+func speed(second int, download float64) core.Speed {
+	return core.Speed{Download: download, Timestamp: time.Unix(int64(second), 0)}
+}
+
+// deliver runs the engine until notifier received count alerts
+func deliver(t *testing.T, engine *alert.Engine, notifier *recordingNotifier, count int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	go engine.Run(ctx)
+	for len(notifier.get()) < count {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected %d alerts, actual: %v", count, notifier.get())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(notifier.get()) != count {
+		t.Fatalf("expected %d alerts, actual: %v", count, notifier.get())
+	}
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []alert.Alert
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, a alert.Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, a)
+	return nil
+}
+
+func (n *recordingNotifier) get() []alert.Alert {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.alerts
+}
+
+type countingHandler struct {
+	mu sync.Mutex
+	i  int
+}
+
+func (h *countingHandler) Handle(error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.i++
+}
+
+func (h *countingHandler) get() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.i
+}
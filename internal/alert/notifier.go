@@ -0,0 +1,30 @@
+package alert
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+)
+
+// Log writes firing alerts at Warn and resolved ones at Info level
+type Log struct {
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+func (l Log) Notify(_ context.Context, a Alert) error {
+	logger := logging.For(l.Logger, "alert").With("rule", a.Rule, "job", a.Job, "description", a.Description,
+		"value", a.Value, "start", a.StartsAt)
+	if a.State == Resolved {
+		logger.Info("alert resolved", "end", a.EndsAt, "duration", a.EndsAt.Sub(a.StartsAt))
+		return nil
+	}
+	logger.Warn("alert firing")
+	return nil
+}
+
+// NotifierFunc allows to use ordinary function as Notifier
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
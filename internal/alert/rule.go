@@ -0,0 +1,166 @@
+package alert
+
+import (
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/analytics"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"time"
+)
+
+// Metric is a value of core.Speed evaluated by the rule
+type Metric string
+
+const (
+	// Download is download speed in Mbps
+	Download Metric = "download"
+	// Upload is upload speed in Mbps
+	Upload Metric = "upload"
+	// Ping is latency in milliseconds
+	Ping Metric = "ping"
+)
+
+func (m Metric) value(s core.Speed) (float64, error) {
+	switch m {
+	case Download:
+		return s.Download, nil
+	case Upload:
+		return s.Upload, nil
+	case Ping:
+		return float64(s.Ping) / float64(time.Millisecond), nil
+	}
+	return 0, fmt.Errorf("unsupported metric: %s", m)
+}
+
+// Condition compares the metric with the threshold
+type Condition string
+
+const (
+	Below Condition = "below"
+	Above Condition = "above"
+)
+
+func (c Condition) breached(value, threshold float64) (bool, error) {
+	switch c {
+	case Below:
+		return value < threshold, nil
+	case Above:
+		return value > threshold, nil
+	}
+	return false, fmt.Errorf("unsupported condition: %s", c)
+}
+
+// Evaluation is the outcome of the rule for recent results
+type Evaluation struct {
+	Firing bool
+	// Value is the evaluated value of the metric, e.g. the percentile
+	Value       float64
+	Description string
+}
+
+// Rule decides whether recent results of the job are degraded
+type Rule interface {
+	Name() string
+	// Evaluate checks results ordered from the oldest to the latest. There is always at least one result
+	Evaluate(results []core.Speed) (Evaluation, error)
+	// Retention returns how many latest results and results from how long before the latest one the rule needs
+	Retention() (count int, window time.Duration)
+}
+
+// Consecutive fires when the metric breached the threshold in Count latest results
+type Consecutive struct {
+	RuleName  string
+	Metric    Metric
+	Condition Condition
+	Threshold float64
+	Count     int
+}
+
+func (r Consecutive) Name() string {
+	return r.RuleName
+}
+
+func (r Consecutive) Evaluate(results []core.Speed) (Evaluation, error) {
+	latest, err := r.Metric.value(results[len(results)-1])
+	if err != nil {
+		return Evaluation{}, err
+	}
+	eval := Evaluation{
+		Value:       latest,
+		Description: fmt.Sprintf("%s %s %v in %d consecutive runs", r.Metric, r.Condition, r.Threshold, r.Count),
+	}
+	if len(results) < r.Count {
+		return eval, nil
+	}
+
+	for _, s := range results[len(results)-r.Count:] {
+		v, err := r.Metric.value(s)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		breached, err := r.Condition.breached(v, r.Threshold)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		if !breached {
+			return eval, nil
+		}
+	}
+	eval.Firing = true
+	return eval, nil
+}
+
+func (r Consecutive) Retention() (int, time.Duration) {
+	return r.Count, 0
+}
+
+// Percentile fires when the percentile of the metric over results from the Window breached the threshold.
+// Window ends at the timestamp of the latest result
+type Percentile struct {
+	RuleName  string
+	Metric    Metric
+	Condition Condition
+	Threshold float64
+	// Percentile is in range (0, 100]
+	Percentile float64
+	Window     time.Duration
+	// MinSamples is the number of results required to evaluate the rule
+	MinSamples int
+}
+
+func (r Percentile) Name() string {
+	return r.RuleName
+}
+
+func (r Percentile) Evaluate(results []core.Speed) (Evaluation, error) {
+	since := results[len(results)-1].Timestamp.Add(-r.Window)
+	values := make([]float64, 0, len(results))
+	for _, s := range results {
+		if s.Timestamp.Before(since) {
+			continue
+		}
+		v, err := r.Metric.value(s)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		values = append(values, v)
+	}
+
+	p := analytics.Percentile(values, r.Percentile)
+	eval := Evaluation{
+		Value:       p,
+		Description: fmt.Sprintf("p%v of %s over %v %s %v", r.Percentile, r.Metric, r.Window, r.Condition, r.Threshold),
+	}
+	if len(values) < r.MinSamples {
+		return eval, nil
+	}
+	breached, err := r.Condition.breached(p, r.Threshold)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	eval.Firing = breached
+	return eval, nil
+}
+
+func (r Percentile) Retention() (int, time.Duration) {
+	return r.MinSamples, r.Window
+}
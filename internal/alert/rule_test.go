@@ -0,0 +1,97 @@
+package alert_test
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"testing"
+	"time"
+)
+
+func TestConsecutive_Evaluate(t *testing.T) {
+	rule := alert.Consecutive{RuleName: "slow", Metric: alert.Download, Condition: alert.Below, Threshold: 300, Count: 3}
+	tests := map[string]struct {
+		downloads []float64
+		firing    bool
+	}{
+		"not enough results":  {downloads: []float64{100, 100}, firing: false},
+		"all below":           {downloads: []float64{100, 100, 100}, firing: true},
+		"latest three below":  {downloads: []float64{400, 100, 100, 100}, firing: true},
+		"one of three above":  {downloads: []float64{100, 400, 100}, firing: false},
+		"equal to threshold":  {downloads: []float64{300, 300, 300}, firing: false},
+		"recovered in latest": {downloads: []float64{100, 100, 100, 350}, firing: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			results := make([]core.Speed, 0, len(tt.downloads))
+			for i, d := range tt.downloads {
+				results = append(results, core.Speed{Download: d, Timestamp: time.Unix(int64(i), 0)})
+			}
+
+			eval, err := rule.Evaluate(results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if eval.Firing != tt.firing {
+				t.Fatalf("expected firing: %t, actual: %t", tt.firing, eval.Firing)
+			}
+			if eval.Value != tt.downloads[len(tt.downloads)-1] {
+				t.Fatalf("expected value of the latest result, actual: %v", eval.Value)
+			}
+		})
+	}
+}
+
+func TestPercentile_Evaluate(t *testing.T) {
+	rule := alert.Percentile{
+		RuleName:   "laggy",
+		Metric:     alert.Ping,
+		Condition:  alert.Above,
+		Threshold:  50,
+		Percentile: 95,
+		Window:     1 * time.Hour,
+		MinSamples: 2,
+	}
+	tests := map[string]struct {
+		// pings in milliseconds, measured every 10 minutes
+		pings  []int
+		firing bool
+		value  float64
+	}{
+		"not enough samples":   {pings: []int{100}, firing: false, value: 100},
+		"p95 above threshold":  {pings: []int{10, 20, 30, 100}, firing: true, value: 100},
+		"p95 below threshold":  {pings: []int{10, 20, 30, 40}, firing: false, value: 40},
+		"spike outside window": {pings: []int{100, 10, 10, 10, 10, 10, 10, 10}, firing: false, value: 10},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			results := make([]core.Speed, 0, len(tt.pings))
+			for i, p := range tt.pings {
+				results = append(results, core.Speed{
+					Ping:      time.Duration(p) * time.Millisecond,
+					Timestamp: time.Unix(0, 0).Add(time.Duration(i) * 10 * time.Minute),
+				})
+			}
+
+			eval, err := rule.Evaluate(results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if eval.Firing != tt.firing {
+				t.Fatalf("expected firing: %t, actual: %t", tt.firing, eval.Firing)
+			}
+			if eval.Value != tt.value {
+				t.Fatalf("expected p95: %v, actual: %v", tt.value, eval.Value)
+			}
+		})
+	}
+}
+
+func TestRule_InvalidMetric(t *testing.T) {
+	rule := alert.Consecutive{RuleName: "invalid", Metric: "jitter", Condition: alert.Below, Count: 1}
+	_, err := rule.Evaluate([]core.Speed{{}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,321 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metric is a value of core.Speed
+type Metric string
+
+const (
+	// Download is download speed in Mbps
+	Download Metric = "download"
+	// Upload is upload speed in Mbps
+	Upload Metric = "upload"
+	// Ping is latency in milliseconds
+	Ping Metric = "ping"
+)
+
+// Metrics are all metrics of core.Speed in order of the report
+var Metrics = []Metric{Download, Upload, Ping}
+
+// Value of the metric measured by the speed test
+func (m Metric) Value(s core.Speed) (float64, error) {
+	switch m {
+	case Download:
+		return s.Download, nil
+	case Upload:
+		return s.Upload, nil
+	case Ping:
+		return float64(s.Ping) / float64(time.Millisecond), nil
+	}
+	return 0, fmt.Errorf("unsupported metric: %s", m)
+}
+
+// Unit of the metric
+func (m Metric) Unit() string {
+	if m == Ping {
+		return "ms"
+	}
+	return "Mbps"
+}
+
+// Percentile uses nearest-rank method, so percentile 0 is the minimum and 100 the maximum. It returns NaN for no values
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Average returns arithmetic mean of the values or NaN for no values
+func Average(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Window is time range [From, To)
+type Window struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (w Window) Duration() time.Duration {
+	return w.To.Sub(w.From)
+}
+
+// Threshold is the worst acceptable value of the metric: the minimum of download and upload or the maximum of ping
+type Threshold struct {
+	Metric Metric
+	Value  float64
+}
+
+// Breached is true when the value is worse than the threshold
+func (t Threshold) Breached(value float64) bool {
+	if t.Metric == Ping {
+		return value > t.Value
+	}
+	return value < t.Value
+}
+
+type Cfg struct {
+	// Interval is the expected time between speed tests. Every result represents the time until the next result of
+	// its series, but not longer than the Interval. Time not represented by any result is unavailable
+	Interval time.Duration
+	// Percentiles of every metric, in range (0, 100]
+	Percentiles []float64
+	// Thresholds of the SLA. Time breaching is counted for every threshold, compliance requires meeting all of them
+	Thresholds []Threshold
+}
+
+// DefaultPercentiles are the lowest, the typical and the highest results without outliers
+var DefaultPercentiles = []float64{5, 50, 95}
+
+// Stats of the single metric
+type Stats struct {
+	Metric      Metric     `json:"metric"`
+	Unit        string     `json:"unit"`
+	Min         float64    `json:"min"`
+	Avg         float64    `json:"avg"`
+	Max         float64    `json:"max"`
+	Percentiles []Quantile `json:"percentiles"`
+}
+
+// Quantile is the value of the metric at the percentile P
+type Quantile struct {
+	P     float64 `json:"p"`
+	Value float64 `json:"value"`
+}
+
+// Breach is time in the window when the results were worse than the threshold
+type Breach struct {
+	Metric    Metric        `json:"metric"`
+	Threshold float64       `json:"threshold"`
+	Duration  time.Duration `json:"-"`
+	// Ratio of the Duration to the whole window
+	Ratio float64 `json:"ratio"`
+}
+
+// MarshalJSON writes Duration in seconds, because JSON has no type of the duration
+func (b Breach) MarshalJSON() ([]byte, error) {
+	type breach Breach
+	return json.Marshal(struct {
+		breach
+		Seconds float64 `json:"duration_seconds"`
+	}{breach(b), b.Duration.Seconds()})
+}
+
+// Report of the results from the window. Stats are empty, when there are no results
+type Report struct {
+	Window Window `json:"window"`
+	Count  int    `json:"count"`
+	// Series is the number of distinct tag sets of the results, e.g. jobs or providers
+	Series int     `json:"series"`
+	Stats  []Stats `json:"stats"`
+	// Availability is the ratio of time represented by results to the whole window, averaged over the series
+	Availability float64 `json:"availability"`
+	// Compliance is the ratio of time represented by results meeting all thresholds to the whole window, averaged over
+	// the series
+	Compliance float64  `json:"compliance"`
+	Breaches   []Breach `json:"breaches"`
+}
+
+// Compute reports results from the window. Results outside the window are ignored
+func Compute(speeds []core.Speed, window Window, cfg Cfg) (Report, error) {
+	if !window.To.After(window.From) {
+		return Report{}, fmt.Errorf("window must end after it starts, from: %v, to: %v", window.From, window.To)
+	}
+	if cfg.Interval <= 0 {
+		return Report{}, fmt.Errorf("interval must be positive, actual: %v", cfg.Interval)
+	}
+	if cfg.Percentiles == nil {
+		cfg.Percentiles = DefaultPercentiles
+	}
+	for _, p := range cfg.Percentiles {
+		if p <= 0 || p > 100 {
+			return Report{}, fmt.Errorf("percentile must be in range (0, 100], actual: %v", p)
+		}
+	}
+	for _, t := range cfg.Thresholds {
+		if _, err := t.Metric.Value(core.Speed{}); err != nil {
+			return Report{}, err
+		}
+	}
+
+	speeds = inWindow(speeds, window)
+	r := Report{Window: window, Count: len(speeds), Series: len(series(speeds)), Stats: make([]Stats, 0, len(Metrics)), Breaches: make([]Breach, 0, len(cfg.Thresholds))}
+	if len(speeds) > 0 {
+		for _, m := range Metrics {
+			r.Stats = append(r.Stats, stats(speeds, m, cfg.Percentiles))
+		}
+	}
+
+	total := window.Duration()
+	r.Availability = ratio(Available(speeds, window, cfg.Interval), total)
+	r.Compliance = ratio(Compliant(speeds, window, cfg.Interval, cfg.Thresholds...), total)
+	for _, t := range cfg.Thresholds {
+		d := TimeBreached(speeds, window, cfg.Interval, t)
+		r.Breaches = append(r.Breaches, Breach{Metric: t.Metric, Threshold: t.Value, Duration: d, Ratio: ratio(d, total)})
+	}
+	return r, nil
+}
+
+// Available is the time in the window represented by any result, averaged over the series
+func Available(speeds []core.Speed, window Window, interval time.Duration) time.Duration {
+	return covered(speeds, window, interval, func(core.Speed) bool { return true })
+}
+
+// Compliant is the time in the window represented by results, which breached none of the thresholds, averaged over
+// the series
+func Compliant(speeds []core.Speed, window Window, interval time.Duration, thresholds ...Threshold) time.Duration {
+	return covered(speeds, window, interval, func(s core.Speed) bool {
+		for _, t := range thresholds {
+			if v, _ := t.Metric.Value(s); t.Breached(v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// TimeBreached is the time in the window represented by results worse than the threshold, e.g. time below
+// the minimum download speed. It is averaged over the series
+func TimeBreached(speeds []core.Speed, window Window, interval time.Duration, threshold Threshold) time.Duration {
+	return covered(speeds, window, interval, func(s core.Speed) bool {
+		v, _ := threshold.Metric.Value(s)
+		return threshold.Breached(v)
+	})
+}
+
+// covered sums time represented by results matching the predicate and averages it over the series. Every result
+// represents the time until the next one of its series, limited by the interval and the end of the window
+func covered(speeds []core.Speed, window Window, interval time.Duration, match func(core.Speed) bool) time.Duration {
+	all := series(inWindow(speeds, window))
+	if len(all) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, speeds := range all {
+		for i, s := range speeds {
+			if !match(s) {
+				continue
+			}
+			end := s.Timestamp.Add(interval)
+			if i+1 < len(speeds) && speeds[i+1].Timestamp.Before(end) {
+				end = speeds[i+1].Timestamp
+			}
+			if end.After(window.To) {
+				end = window.To
+			}
+			sum += end.Sub(s.Timestamp)
+		}
+	}
+	return sum / time.Duration(len(all))
+}
+
+// series splits results by their tags, so results of different jobs or providers do not cut time represented by each
+// other. Order of the results and of the series is preserved
+func series(speeds []core.Speed) [][]core.Speed {
+	out := make([][]core.Speed, 0)
+	index := make(map[string]int)
+	for _, s := range speeds {
+		key := seriesKey(s.Tags)
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, nil)
+		}
+		out[i] = append(out[i], s)
+	}
+	return out
+}
+
+func seriesKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := &strings.Builder{}
+	for _, k := range keys {
+		fmt.Fprintf(b, "%q=%q,", k, tags[k])
+	}
+	return b.String()
+}
+
+// inWindow returns results from the window sorted by their timestamps
+func inWindow(speeds []core.Speed, window Window) []core.Speed {
+	out := make([]core.Speed, 0, len(speeds))
+	for _, s := range speeds {
+		if !s.Timestamp.Before(window.From) && s.Timestamp.Before(window.To) {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out
+}
+
+func stats(speeds []core.Speed, m Metric, percentiles []float64) Stats {
+	values := make([]float64, 0, len(speeds))
+	for _, s := range speeds {
+		v, _ := m.Value(s)
+		values = append(values, v)
+	}
+	s := Stats{Metric: m, Unit: m.Unit(), Min: math.Inf(1), Max: math.Inf(-1), Avg: Average(values)}
+	for _, v := range values {
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+	}
+	s.Percentiles = make([]Quantile, 0, len(percentiles))
+	for _, p := range percentiles {
+		s.Percentiles = append(s.Percentiles, Quantile{P: p, Value: Percentile(values, p)})
+	}
+	return s
+}
+
+func ratio(d, total time.Duration) float64 {
+	return float64(d) / float64(total)
+}
@@ -0,0 +1,279 @@
+package analytics_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/paluszkiewiczB/speedtest/internal/analytics"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// speeds measured every 10 minutes, starting at start
+func speeds(downloads ...float64) []core.Speed {
+	out := make([]core.Speed, 0, len(downloads))
+	for i, d := range downloads {
+		out = append(out, core.Speed{
+			Download:  d,
+			Upload:    d / 10,
+			Ping:      time.Duration(i+1) * time.Millisecond,
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Minute),
+		})
+	}
+	return out
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	tests := map[string]struct {
+		p, expected float64
+	}{
+		"p0":   {p: 0, expected: 15},
+		"p5":   {p: 5, expected: 15},
+		"p30":  {p: 30, expected: 20},
+		"p50":  {p: 50, expected: 35},
+		"p100": {p: 100, expected: 50},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actual := analytics.Percentile(values, tt.p); actual != tt.expected {
+				t.Fatalf("expected: %v, actual: %v", tt.expected, actual)
+			}
+		})
+	}
+
+	if !math.IsNaN(analytics.Percentile(nil, 50)) {
+		t.Fatal("expected NaN for no values")
+	}
+}
+
+func TestAverage(t *testing.T) {
+	if actual := analytics.Average([]float64{1, 2, 6}); actual != 3 {
+		t.Fatalf("expected: 3, actual: %v", actual)
+	}
+	if !math.IsNaN(analytics.Average(nil)) {
+		t.Fatal("expected NaN for no values")
+	}
+}
+
+func TestTimeBreached(t *testing.T) {
+	window := analytics.Window{From: start, To: start.Add(time.Hour)}
+	threshold := analytics.Threshold{Metric: analytics.Download, Value: 100}
+	tests := map[string]struct {
+		speeds   []core.Speed
+		interval time.Duration
+		expected time.Duration
+	}{
+		"none below":             {speeds: speeds(200, 200, 200), interval: 10 * time.Minute, expected: 0},
+		"until the next result":  {speeds: speeds(50, 200, 50), interval: 10 * time.Minute, expected: 20 * time.Minute},
+		"limited by interval":    {speeds: speeds(50, 200, 50), interval: 5 * time.Minute, expected: 10 * time.Minute},
+		"limited by window":      {speeds: speeds(200, 200, 200, 200, 200, 50), interval: time.Hour, expected: 10 * time.Minute},
+		"equal to threshold":     {speeds: speeds(100, 100), interval: 10 * time.Minute, expected: 0},
+		"results outside window": {speeds: append(speeds(50), core.Speed{Download: 50, Timestamp: start.Add(-time.Minute)}), interval: 10 * time.Minute, expected: 10 * time.Minute},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := analytics.TimeBreached(tt.speeds, window, tt.interval, threshold)
+			if actual != tt.expected {
+				t.Fatalf("expected: %v, actual: %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestThreshold_Breached(t *testing.T) {
+	if !(analytics.Threshold{Metric: analytics.Ping, Value: 20}).Breached(21) {
+		t.Fatal("expected ping above the threshold to breach it")
+	}
+	if (analytics.Threshold{Metric: analytics.Upload, Value: 20}).Breached(21) {
+		t.Fatal("expected upload above the threshold not to breach it")
+	}
+}
+
+func TestCompute(t *testing.T) {
+	// 6 results in 2 hours, so the second hour is not available
+	window := analytics.Window{From: start, To: start.Add(2 * time.Hour)}
+	cfg := analytics.Cfg{
+		Interval:    10 * time.Minute,
+		Percentiles: []float64{50},
+		Thresholds: []analytics.Threshold{
+			{Metric: analytics.Download, Value: 100},
+			{Metric: analytics.Ping, Value: 4},
+		},
+	}
+
+	r, err := analytics.Compute(speeds(300, 50, 200, 400, 100, 150), window, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Count != 6 {
+		t.Fatalf("expected 6 results, actual: %d", r.Count)
+	}
+	if r.Availability != 0.5 {
+		t.Fatalf("expected availability: 0.5, actual: %v", r.Availability)
+	}
+	// download of the second result and pings of the fifth and sixth breach thresholds
+	if expected := 0.25; r.Compliance != expected {
+		t.Fatalf("expected compliance: %v, actual: %v", expected, r.Compliance)
+	}
+	download := r.Stats[0]
+	if download.Metric != analytics.Download || download.Min != 50 || download.Max != 400 || download.Avg != 200 {
+		t.Fatalf("unexpected stats of download: %+v", download)
+	}
+	if p50 := download.Percentiles[0]; p50.P != 50 || p50.Value != 150 {
+		t.Fatalf("unexpected p50 of download: %+v", p50)
+	}
+	if ping := r.Stats[2]; ping.Unit != "ms" || ping.Max != 6 {
+		t.Fatalf("unexpected stats of ping: %+v", ping)
+	}
+	if b := r.Breaches[0]; b.Duration != 10*time.Minute {
+		t.Fatalf("expected 10 minutes below download threshold, actual: %v", b.Duration)
+	}
+	if b := r.Breaches[1]; b.Duration != 20*time.Minute {
+		t.Fatalf("expected 20 minutes above ping threshold, actual: %v", b.Duration)
+	}
+}
+
+func TestCompute_Series(t *testing.T) {
+	window := analytics.Window{From: start, To: start.Add(time.Hour)}
+	// two jobs testing at the same time, the second one only in the first half of the window
+	wan := speeds(50, 50, 50, 200, 200, 200)
+	lte := speeds(200, 200, 200)
+	for i := range wan {
+		wan[i] = wan[i].WithTag("job", "wan")
+	}
+	for i := range lte {
+		lte[i] = lte[i].WithTag("job", "lte").WithTag(core.ProviderTag, "ookla")
+	}
+
+	r, err := analytics.Compute(append(wan, lte...), window, analytics.Cfg{
+		Interval:   10 * time.Minute,
+		Thresholds: []analytics.Threshold{{Metric: analytics.Download, Value: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Series != 2 {
+		t.Fatalf("expected 2 series, actual: %d", r.Series)
+	}
+	if r.Availability != 0.75 {
+		t.Fatalf("expected availability: 0.75, actual: %v", r.Availability)
+	}
+	// half of the first series breached the threshold
+	if b := r.Breaches[0]; b.Duration != 15*time.Minute {
+		t.Fatalf("expected 15 minutes below download threshold, actual: %v", b.Duration)
+	}
+}
+
+func TestCompute_NoResults(t *testing.T) {
+	window := analytics.Window{From: start, To: start.Add(time.Hour)}
+	r, err := analytics.Compute(nil, window, analytics.Cfg{Interval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != 0 || len(r.Stats) != 0 || r.Availability != 0 {
+		t.Fatalf("expected empty report, actual: %+v", r)
+	}
+	if err := analytics.WriteJSON(&bytes.Buffer{}, r); err != nil {
+		t.Fatalf("could not write empty report: %v", err)
+	}
+}
+
+func TestCompute_Invalid(t *testing.T) {
+	window := analytics.Window{From: start, To: start.Add(time.Hour)}
+	tests := map[string]struct {
+		window analytics.Window
+		cfg    analytics.Cfg
+	}{
+		"empty window":       {window: analytics.Window{From: start, To: start}, cfg: analytics.Cfg{Interval: time.Minute}},
+		"no interval":        {window: window, cfg: analytics.Cfg{}},
+		"zero percentile":    {window: window, cfg: analytics.Cfg{Interval: time.Minute, Percentiles: []float64{0}}},
+		"unsupported metric": {window: window, cfg: analytics.Cfg{Interval: time.Minute, Thresholds: []analytics.Threshold{{Metric: "jitter"}}}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := analytics.Compute(nil, tt.window, tt.cfg); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	window := analytics.Window{From: start, To: start.Add(time.Hour)}
+	cfg := analytics.Cfg{Interval: 10 * time.Minute, Thresholds: []analytics.Threshold{{Metric: analytics.Download, Value: 100}}}
+	r, err := analytics.Compute(speeds(300, 50, 200), window, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := analytics.Write(buf, r, analytics.Markdown); err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{"- Availability: 50.00%", "| Metric | Min | Avg | p5 | p50 | p95 | Max |", "| download [Mbps] | 50.00 | 183.33 | 50.00 | 200.00 | 300.00 | 300.00 |", "| download | 100 Mbps | 10m0s | 16.67% |"} {
+			if !strings.Contains(buf.String(), expected) {
+				t.Fatalf("expected %q in:\n%s", expected, buf)
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := analytics.Write(buf, r, analytics.CSV); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// header, 4 of the tests, 6 of every metric and 3 of the breach
+		if len(records) != 1+4+3*6+3 {
+			t.Fatalf("unexpected number of records: %d", len(records))
+		}
+		if r := records[len(records)-2]; r[2] != "download" || r[3] != "breach_seconds" || r[4] != "600" {
+			t.Fatalf("unexpected record: %v", r)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := analytics.Write(buf, r, analytics.JSON); err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Count    int `json:"count"`
+			Breaches []struct {
+				Seconds float64 `json:"duration_seconds"`
+			} `json:"breaches"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Count != 3 || decoded.Breaches[0].Seconds != 600 {
+			t.Fatalf("unexpected report: %s", buf)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if err := analytics.Write(&bytes.Buffer{}, r, "xml"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := analytics.ParseFormat("csv"); err != nil || f != analytics.CSV {
+		t.Fatalf("expected csv, actual: %v, err: %v", f, err)
+	}
+	if _, err := analytics.ParseFormat("xml"); err == nil {
+		t.Fatal("expected error")
+	}
+}
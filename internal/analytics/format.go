@@ -0,0 +1,128 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format of the written report
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	CSV      Format = "csv"
+	JSON     Format = "json"
+)
+
+// ParseFormat returns the format or error, when it is not supported
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Markdown, CSV, JSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported report format: %s", s)
+}
+
+// Write writes the report in the format
+func Write(w io.Writer, r Report, format Format) error {
+	switch format {
+	case Markdown:
+		return WriteMarkdown(w, r)
+	case CSV:
+		return WriteCSV(w, r)
+	case JSON:
+		return WriteJSON(w, r)
+	}
+	return fmt.Errorf("unsupported report format: %s", format)
+}
+
+// WriteMarkdown writes the report as a document with tables of the stats and breaches
+func WriteMarkdown(w io.Writer, r Report) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# Speed test report\n\n")
+	fmt.Fprintf(b, "%s - %s\n\n", r.Window.From.Format(time.RFC3339), r.Window.To.Format(time.RFC3339))
+	fmt.Fprintf(b, "- Tests: %d\n", r.Count)
+	fmt.Fprintf(b, "- Series: %d\n", r.Series)
+	fmt.Fprintf(b, "- Availability: %s\n", percent(r.Availability))
+	fmt.Fprintf(b, "- Compliance: %s\n", percent(r.Compliance))
+
+	if len(r.Stats) > 0 {
+		fmt.Fprintf(b, "\n## Stats\n\n| Metric | Min | Avg |")
+		for _, q := range r.Stats[0].Percentiles {
+			fmt.Fprintf(b, " p%s |", number(q.P))
+		}
+		fmt.Fprintf(b, " Max |\n|---|---:|---:|%s---:|\n", strings.Repeat("---:|", len(r.Stats[0].Percentiles)))
+		for _, s := range r.Stats {
+			fmt.Fprintf(b, "| %s [%s] | %.2f | %.2f |", s.Metric, s.Unit, s.Min, s.Avg)
+			for _, q := range s.Percentiles {
+				fmt.Fprintf(b, " %.2f |", q.Value)
+			}
+			fmt.Fprintf(b, " %.2f |\n", s.Max)
+		}
+	}
+
+	if len(r.Breaches) > 0 {
+		fmt.Fprintf(b, "\n## Breaches\n\n| Metric | Threshold | Time | Share |\n|---|---:|---:|---:|\n")
+		for _, br := range r.Breaches {
+			fmt.Fprintf(b, "| %s | %s %s | %v | %s |\n", br.Metric, number(br.Threshold), br.Metric.Unit(),
+				br.Duration.Round(time.Second), percent(br.Ratio))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes the report in long format with columns: from, to, metric, statistic and value. Statistics of
+// the whole window have metric "tests", durations are in seconds
+func WriteCSV(w io.Writer, r Report) error {
+	from, to := r.Window.From.Format(time.RFC3339), r.Window.To.Format(time.RFC3339)
+	records := [][]string{{"from", "to", "metric", "statistic", "value"}}
+	row := func(metric, statistic string, value float64) {
+		records = append(records, []string{from, to, metric, statistic, number(value)})
+	}
+	row("tests", "count", float64(r.Count))
+	row("tests", "series", float64(r.Series))
+	row("tests", "availability", r.Availability)
+	row("tests", "compliance", r.Compliance)
+	for _, s := range r.Stats {
+		metric := string(s.Metric)
+		row(metric, "min", s.Min)
+		row(metric, "avg", s.Avg)
+		for _, q := range s.Percentiles {
+			row(metric, "p"+number(q.P), q.Value)
+		}
+		row(metric, "max", s.Max)
+	}
+	for _, b := range r.Breaches {
+		metric := string(b.Metric)
+		row(metric, "threshold", b.Threshold)
+		row(metric, "breach_seconds", b.Duration.Seconds())
+		row(metric, "breach_ratio", b.Ratio)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("could not write csv report: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON object
+func WriteJSON(w io.Writer, r Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func percent(ratio float64) string {
+	return fmt.Sprintf("%.2f%%", ratio*100)
+}
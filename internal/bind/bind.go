@@ -0,0 +1,82 @@
+package bind
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Cfg selects the network interface used by outgoing connections. Zero value means no binding,
+// so the operating system picks the route
+type Cfg struct {
+	// Address is the local IP address used as the source of connections
+	Address string
+	// Interface is the name of the network interface, e.g. eth0. Binding to the device requires Linux
+	Interface string
+}
+
+// Enabled returns true when any binding is configured
+func (c Cfg) Enabled() bool {
+	return c.Address != "" || c.Interface != ""
+}
+
+func (c Cfg) String() string {
+	switch {
+	case c.Address != "" && c.Interface != "":
+		return fmt.Sprintf("%s (%s)", c.Interface, c.Address)
+	case c.Interface != "":
+		return c.Interface
+	default:
+		return c.Address
+	}
+}
+
+// Dialer returns net.Dialer which opens connections from configured address and interface
+func Dialer(cfg Cfg) (*net.Dialer, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if cfg.Address != "" {
+		ip := net.ParseIP(cfg.Address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source address: %q", cfg.Address)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	if cfg.Interface != "" {
+		if _, err := net.InterfaceByName(cfg.Interface); err != nil {
+			return nil, fmt.Errorf("invalid interface: %q: %w", cfg.Interface, err)
+		}
+		iface := cfg.Interface
+		dialer.Control = func(_, _ string, c syscall.RawConn) error {
+			var bindErr error
+			err := c.Control(func(fd uintptr) {
+				bindErr = bindToDevice(fd, iface)
+			})
+			if err != nil {
+				return err
+			}
+			return bindErr
+		}
+	}
+
+	return dialer, nil
+}
+
+// Client returns http.Client which opens connections from configured address and interface.
+// It returns http.DefaultClient if binding is not enabled
+func Client(cfg Cfg) (*http.Client, error) {
+	if !cfg.Enabled() {
+		return http.DefaultClient, nil
+	}
+
+	dialer, err := Dialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}, nil
+}
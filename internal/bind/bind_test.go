@@ -0,0 +1,60 @@
+package bind_test
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/bind"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Address(t *testing.T) {
+	var remote string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := bind.Client(bind.Cfg{Address: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "127.0.0.1" {
+		t.Fatalf("expected connection from 127.0.0.1, actual: %s", host)
+	}
+}
+
+func TestClient_Disabled(t *testing.T) {
+	client, err := bind.Client(bind.Cfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client != http.DefaultClient {
+		t.Fatal("expected default client when binding is not enabled")
+	}
+}
+
+func TestDialer_Invalid(t *testing.T) {
+	cfgs := map[string]bind.Cfg{
+		"address":   {Address: "not-an-ip"},
+		"interface": {Interface: "does-not-exist0"},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			_, err := bind.Dialer(cfg)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build linux
+// +build linux
+
+package bind
+
+import "syscall"
+
+func bindToDevice(fd uintptr, iface string) error {
+	return syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+}
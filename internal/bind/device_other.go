@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package bind
+
+import (
+	"errors"
+	"runtime"
+)
+
+func bindToDevice(_ uintptr, iface string) error {
+	return errors.New("binding to interface " + iface + " is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping describes where to find fields of core.Speed in the command output
+type Mapping struct {
+	// Download and Upload are scaled to Mbps
+	Download, Upload Field
+	// Ping is scaled to milliseconds
+	Ping Field
+	// Timestamp is either RFC 3339 string or unix time in seconds. Start of the command is used when Path is empty
+	Timestamp Field
+}
+
+// Field is located by Path. For JSON, Path is a dot separated list of keys and array indexes, e.g. download.bandwidth.
+// For CSV, Path is either a column name from the header or a zero-based column index
+type Field struct {
+	Path string
+	// Scale multiplies the value. Zero value is treated as 1
+	Scale float64
+}
+
+func (m Mapping) paths() []string {
+	paths := []string{m.Download.Path, m.Upload.Path, m.Ping.Path}
+	if m.Timestamp.Path != "" {
+		paths = append(paths, m.Timestamp.Path)
+	}
+	return paths
+}
+
+func (m Mapping) toSpeed(values map[string]string, start time.Time) (core.Speed, error) {
+	download, err := m.Download.float(values)
+	if err != nil {
+		return core.InvalidSpeed, err
+	}
+	upload, err := m.Upload.float(values)
+	if err != nil {
+		return core.InvalidSpeed, err
+	}
+	ping, err := m.Ping.float(values)
+	if err != nil {
+		return core.InvalidSpeed, err
+	}
+
+	timestamp := start
+	if m.Timestamp.Path != "" {
+		timestamp, err = m.Timestamp.time(values)
+		if err != nil {
+			return core.InvalidSpeed, err
+		}
+	}
+
+	return core.Speed{
+		Download:  download,
+		Upload:    upload,
+		Ping:      time.Duration(ping * float64(time.Millisecond)),
+		Timestamp: timestamp,
+	}, nil
+}
+
+func (f Field) float(values map[string]string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(values[f.Path]), 64)
+	if err != nil {
+		return -1, fmt.Errorf("field: %s is not a number: %w", f.Path, err)
+	}
+	if f.Scale == 0 {
+		return v, nil
+	}
+	return v * f.Scale, nil
+}
+
+func (f Field) time(values map[string]string) (time.Time, error) {
+	v := strings.TrimSpace(values[f.Path])
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("field: %s is neither RFC 3339 time nor unix time: %s", f.Path, v)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+// parseJSON returns values found at paths in JSON document
+func parseJSON(output []byte, paths []string) (map[string]string, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for _, path := range paths {
+		v, err := find(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		values[path] = fmt.Sprint(v)
+	}
+	return values, nil
+}
+
+func find(doc interface{}, path string) (interface{}, error) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("field: %s not found", path)
+			}
+			current = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("field: %s not found, invalid index: %s", path, key)
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("field: %s not found", path)
+		}
+	}
+	return current, nil
+}
+
+// parseCSV returns values from the last record. When any path is not a column index, the first record is a header
+func parseCSV(output []byte, paths []string) (map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(output))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	withHeader := false
+	for _, path := range paths {
+		if _, err := strconv.Atoi(path); err != nil {
+			withHeader = true
+		}
+	}
+	if withHeader {
+		if len(records) < 1 {
+			return nil, errors.New("missing csv header")
+		}
+		for i, name := range records[0] {
+			columns[name] = i
+		}
+		records = records[1:]
+	}
+	// numeric paths are indexes of the columns, also when they are mixed with names
+	for _, path := range paths {
+		if i, err := strconv.Atoi(path); err == nil {
+			columns[path] = i
+		}
+	}
+	if len(records) < 1 {
+		return nil, errors.New("missing csv record")
+	}
+
+	last := records[len(records)-1]
+	values := make(map[string]string)
+	for _, path := range paths {
+		i, ok := columns[path]
+		if !ok || i < 0 || i >= len(last) {
+			return nil, fmt.Errorf("column: %s not found", path)
+		}
+		values[path] = last[i]
+	}
+	return values, nil
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	RunPhase   core.Phase = "running command"
+	ParsePhase core.Phase = "parsing command output"
+)
+
+type Format string
+
+const (
+	JSON Format = "JSON"
+	CSV  Format = "CSV"
+)
+
+func NewSpeedTester(cfg Cfg) *SpeedTester {
+	return &SpeedTester{cfg: cfg}
+}
+
+type Cfg struct {
+	Command string
+	Args    []string
+	// Timeout limits execution time of the command. Zero value means no limit
+	Timeout time.Duration
+	Format  Format
+	Mapping Mapping
+}
+
+// SpeedTester runs external command, e.g. vendor CLI, and maps its output into core.Speed
+type SpeedTester struct {
+	cfg Cfg
+}
+
+// ExitError is returned when the command exits with non-zero code
+type ExitError struct {
+	Command string
+	Code    int
+	Stderr  string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command: %s exited with code: %d, stderr: %s", e.Command, e.Code, e.Stderr)
+}
+
+func (t *SpeedTester) Test(ctx context.Context) (core.Speed, error) {
+	measurementTime := time.Now()
+	stdout, err := t.run(ctx)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(RunPhase, err)
+	}
+
+	var values map[string]string
+	switch t.cfg.Format {
+	case JSON:
+		values, err = parseJSON(stdout, t.cfg.Mapping.paths())
+	case CSV:
+		values, err = parseCSV(stdout, t.cfg.Mapping.paths())
+	default:
+		err = fmt.Errorf("unsupported output format: %s", t.cfg.Format)
+	}
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(ParsePhase, err)
+	}
+
+	speed, err := t.cfg.Mapping.toSpeed(values, measurementTime)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(ParsePhase, err)
+	}
+	return speed, nil
+}
+
+func (t *SpeedTester) run(ctx context.Context) ([]byte, error) {
+	if t.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.cfg.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, t.cfg.Command, t.cfg.Args...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, &ExitError{
+			Command: t.cfg.Command,
+			Code:    exitErr.ExitCode(),
+			Stderr:  strings.TrimSpace(stderr.String()),
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
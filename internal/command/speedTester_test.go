@@ -0,0 +1,200 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/paluszkiewiczB/speedtest/internal/command"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const ooklaJSON = `{"type":"result","timestamp":"2021-12-11T19:04:30Z","ping":{"jitter":0.5,"latency":12.5},
+"download":{"bandwidth":12500000,"bytes":100},"upload":{"bandwidth":2500000,"bytes":100}}`
+
+var ooklaMapping = command.Mapping{
+	Download:  command.Field{Path: "download.bandwidth", Scale: 0.000008},
+	Upload:    command.Field{Path: "upload.bandwidth", Scale: 0.000008},
+	Ping:      command.Field{Path: "ping.latency"},
+	Timestamp: command.Field{Path: "timestamp"},
+}
+
+func TestSpeedTester_Test(t *testing.T) {
+	t.Run("should map command output into speed", func(t *testing.T) {
+		tests := map[string]struct {
+			stdout  string
+			format  command.Format
+			mapping command.Mapping
+			want    core.Speed
+		}{
+			"when output is json": {
+				stdout:  ooklaJSON,
+				format:  command.JSON,
+				mapping: ooklaMapping,
+				want:    core.Speed{Download: 100, Upload: 20, Ping: 12500 * time.Microsecond, Timestamp: time.Date(2021, 12, 11, 19, 4, 30, 0, time.UTC)},
+			},
+			"when output is json with array": {
+				stdout: `{"results":[{"down":5,"up":1,"ping":3,"time":1639249470}]}`,
+				format: command.JSON,
+				mapping: command.Mapping{
+					Download:  command.Field{Path: "results.0.down"},
+					Upload:    command.Field{Path: "results.0.up"},
+					Ping:      command.Field{Path: "results.0.ping"},
+					Timestamp: command.Field{Path: "results.0.time"},
+				},
+				want: core.Speed{Download: 5, Upload: 1, Ping: 3 * time.Millisecond, Timestamp: time.Unix(1639249470, 0)},
+			},
+			"when output is csv with header": {
+				stdout: "download,upload,ping,time\n9.5, 4.5, 58, 2021-12-11T20:04:30+01:00\n",
+				format: command.CSV,
+				mapping: command.Mapping{
+					Download:  command.Field{Path: "download"},
+					Upload:    command.Field{Path: "upload"},
+					Ping:      command.Field{Path: "ping"},
+					Timestamp: command.Field{Path: "time"},
+				},
+				want: core.Speed{Download: 9.5, Upload: 4.5, Ping: 58 * time.Millisecond, Timestamp: time.Date(2021, 12, 11, 19, 4, 30, 0, time.UTC)},
+			},
+			"when output is csv without header": {
+				stdout: "\"server\",\"3000\",\"4000\",\"20\"\n",
+				format: command.CSV,
+				mapping: command.Mapping{
+					Download: command.Field{Path: "1", Scale: 0.001},
+					Upload:   command.Field{Path: "2", Scale: 0.001},
+					Ping:     command.Field{Path: "3"},
+				},
+				want: core.Speed{Download: 3, Upload: 4, Ping: 20 * time.Millisecond},
+			},
+			"when output is csv with index after name": {
+				stdout: "download,upload,ping\n9.5, 4.5, 58\n",
+				format: command.CSV,
+				mapping: command.Mapping{
+					Download: command.Field{Path: "download"},
+					Upload:   command.Field{Path: "1"},
+					Ping:     command.Field{Path: "2"},
+				},
+				want: core.Speed{Download: 9.5, Upload: 4.5, Ping: 58 * time.Millisecond},
+			},
+			"when output is csv with index before name": {
+				stdout: "download,upload,ping\n9.5, 4.5, 58\n",
+				format: command.CSV,
+				mapping: command.Mapping{
+					Download: command.Field{Path: "0"},
+					Upload:   command.Field{Path: "upload"},
+					Ping:     command.Field{Path: "2"},
+				},
+				want: core.Speed{Download: 9.5, Upload: 4.5, Ping: 58 * time.Millisecond},
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				tester := command.NewSpeedTester(helperCfg(t, helper{stdout: tt.stdout}, tt.format, tt.mapping))
+				start := time.Now()
+				speed, err := tester.Test(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if tt.want.Timestamp.IsZero() {
+					if speed.Timestamp.Before(start) {
+						t.Errorf("expected timestamp of command start, actual: %v", speed.Timestamp)
+					}
+					speed.Timestamp = time.Time{}
+				}
+				if !speed.Timestamp.Equal(tt.want.Timestamp) {
+					t.Errorf("expected timestamp: %v, actual: %v", tt.want.Timestamp, speed.Timestamp)
+				}
+				speed.Timestamp, tt.want.Timestamp = time.Time{}, time.Time{}
+				if !cmp.Equal(speed, tt.want) {
+					t.Errorf("expected speed: %v, actual: %v", tt.want, speed)
+				}
+			})
+		}
+	})
+
+	t.Run("should return exit code and stderr", func(t *testing.T) {
+		tester := command.NewSpeedTester(helperCfg(t, helper{stderr: "license not accepted", exit: 3}, command.JSON, ooklaMapping))
+
+		_, err := tester.Test(context.Background())
+		var exitErr *command.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected *command.ExitError, actual: %v", err)
+		}
+		if exitErr.Code != 3 || exitErr.Stderr != "license not accepted" {
+			t.Fatalf("expected exit code 3 and stderr, actual: %v", exitErr)
+		}
+		if e := core.AsError(err); e == nil || e.Phase != command.RunPhase {
+			t.Fatalf("expected error of phase: %s, actual: %v", command.RunPhase, err)
+		}
+	})
+
+	t.Run("should time out", func(t *testing.T) {
+		cfg := helperCfg(t, helper{sleep: time.Minute}, command.JSON, ooklaMapping)
+		cfg.Timeout = 100 * time.Millisecond
+
+		_, err := command.NewSpeedTester(cfg).Test(context.Background())
+		if e := core.AsError(err); e == nil || e.Kind != core.TimeoutKind {
+			t.Fatalf("expected timeout, actual: %v", err)
+		}
+	})
+
+	t.Run("should fail when field is missing", func(t *testing.T) {
+		tests := map[string]struct {
+			stdout string
+			format command.Format
+		}{
+			"when output is json":    {stdout: `{"download":{}}`, format: command.JSON},
+			"when output is csv":     {stdout: "download\n1\n", format: command.CSV},
+			"when output is invalid": {stdout: "not a json", format: command.JSON},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				tester := command.NewSpeedTester(helperCfg(t, helper{stdout: tt.stdout}, tt.format, ooklaMapping))
+
+				_, err := tester.Test(context.Background())
+				if e := core.AsError(err); e == nil || e.Phase != command.ParsePhase {
+					t.Fatalf("expected error of phase: %s, actual: %v", command.ParsePhase, err)
+				}
+			})
+		}
+	})
+}
+
+type helper struct {
+	stdout, stderr string
+	exit           int
+	sleep          time.Duration
+}
+
+// helperCfg runs TestHelperProcess as the command
+func helperCfg(t *testing.T, h helper, format command.Format, mapping command.Mapping) command.Cfg {
+	t.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	t.Setenv("HELPER_STDOUT", h.stdout)
+	t.Setenv("HELPER_STDERR", h.stderr)
+	t.Setenv("HELPER_EXIT", strconv.Itoa(h.exit))
+	t.Setenv("HELPER_SLEEP", h.sleep.String())
+	return command.Cfg{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess"},
+		Format:  format,
+		Mapping: mapping,
+	}
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	sleep, _ := time.ParseDuration(os.Getenv("HELPER_SLEEP"))
+	time.Sleep(sleep)
+	fmt.Fprint(os.Stdout, os.Getenv("HELPER_STDOUT"))
+	fmt.Fprint(os.Stderr, os.Getenv("HELPER_STDERR"))
+	code, _ := strconv.Atoi(os.Getenv("HELPER_EXIT"))
+	os.Exit(code)
+}
@@ -2,53 +2,219 @@ package core
 
 import (
 	"context"
-	"errors"
-	"log"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
 )
 
+// Boot schedules speed tests and pushes their results to storage until ctx is cancelled. Then it shuts down:
+// stops scheduling, waits up to Config.GracePeriod for running tests, pushes pending results and closes storages.
+// Errors of the shutdown are passed to errH before Boot returns
 func Boot(ctx context.Context, cfg Config, scheduler Scheduler, tester SpeedTester, storage Storage, errH ErrorHandler) error {
-	speedC := make(chan Speed)
+	// tests outlive ctx for the grace period, so they use their own context
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	logger := logging.For(cfg.Logger, "core")
+	pushTimeout := cfg.PushTimeout
+	if pushTimeout <= 0 {
+		pushTimeout = time.Minute
+	}
+	results := newQueue(cfg.Queue, cfg.QueueObserver, logger)
 	testErrC := make(chan error)
-	defer func() {
-		err := scheduler.Close()
+	errorsHandled := handleErrors(testErrC, errH)
+	running := &tasks{}
+	storages := []Storage{storage}
+
+	jobs := cfg.Jobs
+	if len(jobs) == 0 {
+		jobs = []Job{{Name: DefaultJobName, Interval: cfg.SpeedTestInterval, Tester: tester}}
+	}
+	var scheduleErr error
+	for _, job := range jobs {
+		job := job
+		if job.Storage == nil {
+			job.Storage = storage
+		} else {
+			storages = append(storages, job.Storage)
+		}
+		var lastRun time.Time
+		err := scheduler.Schedule(ctx, job.Name, job.Interval, func() {
+			if !running.start() {
+				return
+			}
+			defer running.finish()
+			now := time.Now()
+			if missed := missedRuns(lastRun, now, job.Interval); missed > 0 {
+				cfg.Bus.Publish(TickSkipped{Job: job.Name, Missed: missed})
+			}
+			lastRun = now
+			cfg.Bus.Publish(TestStarted{Job: job.Name, Time: now})
+			ctx, span := otel.Tracer(instrumentation).Start(WithJob(runCtx, job.Name), "speedtest.run", trace.WithAttributes(attribute.String("job", job.Name)))
+			speeds, errs := TestAll(ctx, job.Tester)
+			for _, err := range errs {
+				span.RecordError(err)
+			}
+			if len(errs) > 0 {
+				span.SetStatus(codes.Error, fmt.Sprintf("%d speed tests failed", len(errs)))
+			}
+			span.End()
+			for _, err := range errs {
+				cfg.Bus.Publish(TestFailed{Job: job.Name, Err: err})
+				testErrC <- fmt.Errorf("%s: %w", job.Name, err)
+			}
+			for _, s := range speeds {
+				r := result{job: job, speed: job.tag(s), run: span.SpanContext()}
+				cfg.Bus.Publish(ResultProduced{Job: job.Name, Speed: r.speed})
+				results.put(r)
+			}
+		})
 		if err != nil {
-			log.Printf("error when closing scheduler: %v", err)
+			scheduleErr = fmt.Errorf("could not schedule task for speedtest: %s", job.Name)
+			break
 		}
-		close(testErrC)
-		close(speedC)
-	}()
-	handleErrors(testErrC, errH)
+	}
 
-	err := scheduler.Schedule(ctx, "SpeedTest", cfg.SpeedTestInterval, func() {
-		s, err := tester.Test(ctx)
-		if err != nil {
-			testErrC <- err
-			return
+	if scheduleErr == nil {
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Info("context cancelled, shutting down")
+				break loop
+			case r := <-results.results():
+				results.taken()
+				push(pushTimeout, r, cfg.Bus, testErrC, logger)
+			}
 		}
-		speedC <- s
-	})
+	}
+
+	err := scheduler.Close()
 	if err != nil {
-		return errors.New("could not schedule task for speedtest")
+		logger.Error("could not close scheduler", "err", err)
 	}
+	finished := running.stop()
+	grace := time.NewTimer(cfg.GracePeriod)
+	defer grace.Stop()
+	graceC := grace.C
+drain:
 	for {
 		select {
-		case <-ctx.Done():
-			log.Println("context cancelled, exiting")
-			return nil
-		case s := <-speedC:
-			log.Printf("speedtest result: %v", s)
-			err := storage.Push(ctx, s)
-			if err != nil {
-				testErrC <- err
-			}
+		case r := <-results.results():
+			results.taken()
+			push(pushTimeout, r, cfg.Bus, testErrC, logger)
+		case <-graceC:
+			graceC = nil
+			logger.Warn("grace period exceeded, cancelling running speed tests", "grace_period", cfg.GracePeriod)
+			cancelRun()
+		case <-finished:
+			break drain
+		}
+	}
+	// all tests finished, so nothing is added to the queue anymore
+	for len(results.results()) > 0 {
+		r := <-results.results()
+		results.taken()
+		push(pushTimeout, r, cfg.Bus, testErrC, logger)
+	}
+
+	for _, s := range storages {
+		err := s.Close()
+		if err != nil {
+			testErrC <- fmt.Errorf("could not close storage: %w", err)
 		}
 	}
+	close(testErrC)
+	<-errorsHandled
+	logger.Info("shutdown finished")
+	return scheduleErr
+}
+
+const instrumentation = "github.com/paluszkiewiczB/speedtest/internal/core"
+
+// result is a speed measured by the job. Run is the span of the test, so the push is traced within the same trace
+type result struct {
+	job   Job
+	speed Speed
+	run   trace.SpanContext
+}
+
+// push stores the result with context limited by the timeout, which is not cancelled together with the tests
+func push(timeout time.Duration, r result, bus *Bus, errC chan<- error, logger *logging.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	logger.Info("speed test result", "job", r.job.Name, "download", r.speed.Download, "upload", r.speed.Upload,
+		"ping", r.speed.Ping, "timestamp", r.speed.Timestamp)
+	ctx, span := otel.Tracer(instrumentation).Start(trace.ContextWithSpanContext(ctx, r.run), "storage.push",
+		trace.WithAttributes(attribute.String("job", r.job.Name)))
+	err := r.job.Storage.Push(ctx, r.speed)
+	tracing.End(span, err)
+	if err != nil {
+		err = Classify(StoragePhase, err)
+		bus.Publish(PushFailed{Job: r.job.Name, Speed: r.speed, Err: err})
+		errC <- fmt.Errorf("%s: %w", r.job.Name, err)
+		return
+	}
+	bus.Publish(PushSucceeded{Job: r.job.Name, Speed: r.speed})
+}
+
+// missedRuns returns how many runs of the job were skipped between last and now. Scheduler drops ticks when
+// the task runs longer than the interval
+func missedRuns(last, now time.Time, interval time.Duration) int {
+	if last.IsZero() || interval <= 0 {
+		return 0
+	}
+	return int(now.Sub(last)/interval) - 1
+}
+
+// tasks tracks running jobs, so Boot can wait for them before closing storages
+type tasks struct {
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// start returns false if the task must not run, because shutdown has already begun
+func (t *tasks) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+func (t *tasks) finish() {
+	t.wg.Done()
+}
+
+// stop prevents new tasks from starting. Returned channel is closed when all running tasks finished
+func (t *tasks) stop() <-chan struct{} {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+	return finished
 }
 
-func handleErrors(c chan error, h ErrorHandler) {
+// handleErrors passes errors from c to h. Returned channel is closed when c is closed and all errors were handled
+func handleErrors(c chan error, h ErrorHandler) <-chan struct{} {
+	handled := make(chan struct{})
 	go func() {
+		defer close(handled)
 		for err := range c {
 			h.Handle(err)
 		}
 	}()
+	return handled
 }
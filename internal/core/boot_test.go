@@ -5,6 +5,9 @@ import (
 	"errors"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/schedule"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"testing"
 	"time"
 )
@@ -98,6 +101,33 @@ func Test_BootWithSaveErrors(t *testing.T) {
 	}
 }
 
+func Test_BootTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	scheduler := schedule.NewScheduler()
+	err := core.Boot(ctx, core.Config{SpeedTestInterval: time.Hour}, scheduler, &dummyTester{}, newInMemoryStorage(), &countingHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	run, push := spans["speedtest.run"], spans["storage.push"]
+	if run == nil || push == nil {
+		t.Fatalf("expected spans of the run and the push, actual: %v", spans)
+	}
+	if push.Parent().SpanID() != run.SpanContext().SpanID() {
+		t.Fatalf("expected push to be a child of the run")
+	}
+}
+
 type dummyTester struct {
 }
 
@@ -111,7 +141,8 @@ func newInMemoryStorage() *inMemoryStorage {
 }
 
 type inMemoryStorage struct {
-	s []core.Speed
+	s      []core.Speed
+	closed bool
 }
 
 func (i *inMemoryStorage) Push(_ context.Context, speed core.Speed) error {
@@ -120,6 +151,7 @@ func (i *inMemoryStorage) Push(_ context.Context, speed core.Speed) error {
 }
 
 func (i *inMemoryStorage) Close() error {
+	i.closed = true
 	return nil
 }
 
@@ -160,3 +192,222 @@ func (f *failingStorage) Push(_ context.Context, _ core.Speed) error {
 func (f *failingStorage) Close() error {
 	return errors.New("storage failed")
 }
+
+func Test_BootWithMultiTester(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := newInMemoryStorage()
+
+	handler := &countingHandler{}
+	scheduler := schedule.NewScheduler()
+	t.Cleanup(func() {
+		_ = scheduler.Close()
+	})
+	tester := core.NewComposite(
+		core.NamedTester{Name: "first", Tester: &dummyTester{}},
+		core.NamedTester{Name: "second", Tester: &dummyTester{}},
+		core.NamedTester{Name: "failing", Tester: &failingTester{}},
+	)
+	err := core.Boot(ctx, core.Config{SpeedTestInterval: 1 * time.Hour}, scheduler, tester, storage, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 2 {
+		t.Fatalf("expected to have 2 stored speedtest results, actual: %d", len(storage.s))
+	}
+	if handler.i != 1 {
+		t.Fatalf("expected 1 error handled, actual: %d", handler.i)
+	}
+}
+
+func Test_BootWithJobs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := newInMemoryStorage()
+
+	handler := &countingHandler{}
+	scheduler := schedule.NewScheduler()
+	t.Cleanup(func() {
+		_ = scheduler.Close()
+	})
+	cfg := core.Config{Jobs: []core.Job{
+		{Name: "eth0", Interval: 1 * time.Hour, Tester: &dummyTester{}, Tags: map[string]string{"interface": "eth0"}},
+		{Name: "wlan0", Interval: 1 * time.Hour, Tester: &dummyTester{}, Tags: map[string]string{"interface": "wlan0"}},
+	}}
+	err := core.Boot(ctx, cfg, scheduler, nil, storage, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 2 {
+		t.Fatalf("expected to have 2 stored speedtest results, actual: %d", len(storage.s))
+	}
+	interfaces := map[string]bool{}
+	for _, s := range storage.s {
+		interfaces[s.Tags["interface"]] = true
+	}
+	if !interfaces["eth0"] || !interfaces["wlan0"] {
+		t.Fatalf("expected results tagged with both interfaces, actual: %v", interfaces)
+	}
+}
+
+func Test_BootWithJobStorage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	shared := newInMemoryStorage()
+	own := newInMemoryStorage()
+
+	handler := &countingHandler{}
+	scheduler := schedule.NewScheduler()
+	t.Cleanup(func() {
+		_ = scheduler.Close()
+	})
+	cfg := core.Config{Jobs: []core.Job{
+		{Name: "shared", Interval: 1 * time.Hour, Tester: &dummyTester{}},
+		{Name: "own", Interval: 1 * time.Hour, Tester: &dummyTester{}, Storage: own},
+		{Name: "failing", Interval: 1 * time.Hour, Tester: &dummyTester{}, Storage: &failingStorage{}},
+	}}
+	err := core.Boot(ctx, cfg, scheduler, nil, shared, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(shared.s) != 1 {
+		t.Fatalf("expected to have 1 result in shared storage, actual: %d", len(shared.s))
+	}
+	if len(own.s) != 1 {
+		t.Fatalf("expected to have 1 result in job storage, actual: %d", len(own.s))
+	}
+	if handler.i != 2 {
+		t.Fatalf("expected push and close errors handled, actual: %d", handler.i)
+	}
+}
+
+func Test_BootWaitsForRunningTest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := newInMemoryStorage()
+
+	handler := &countingHandler{}
+	cfg := core.Config{SpeedTestInterval: 1 * time.Hour, GracePeriod: 1 * time.Second}
+	err := core.Boot(ctx, cfg, schedule.NewScheduler(), &slowTester{d: 50 * time.Millisecond}, storage, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 1 {
+		t.Fatalf("expected result of running test to be stored, actual: %d", len(storage.s))
+	}
+	if !storage.closed {
+		t.Fatal("expected storage to be closed")
+	}
+	if handler.i != 0 {
+		t.Fatalf("expected 0 errors count, actual: %d", handler.i)
+	}
+}
+
+func Test_BootCancelsTestAfterGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := newInMemoryStorage()
+
+	handler := &countingHandler{}
+	cfg := core.Config{SpeedTestInterval: 1 * time.Hour, GracePeriod: 10 * time.Millisecond}
+	err := core.Boot(ctx, cfg, schedule.NewScheduler(), &slowTester{d: 1 * time.Hour}, storage, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 0 {
+		t.Fatalf("expected 0 stored results, actual: %d", len(storage.s))
+	}
+	if !storage.closed {
+		t.Fatal("expected storage to be closed")
+	}
+	if handler.i != 1 {
+		t.Fatalf("expected cancelled test to be reported, actual errors: %d", handler.i)
+	}
+}
+
+func Test_BootStoresResultsOfTestsFinishedAfterGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := &contextStorage{inMemoryStorage: newInMemoryStorage()}
+
+	handler := &countingHandler{}
+	cfg := core.Config{SpeedTestInterval: 1 * time.Hour, GracePeriod: 10 * time.Millisecond, PushTimeout: 1 * time.Second}
+	err := core.Boot(ctx, cfg, schedule.NewScheduler(), &stubbornTester{d: 50 * time.Millisecond}, storage, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 1 {
+		t.Fatalf("expected result of the test finished after cancellation to be stored, actual: %d", len(storage.s))
+	}
+	if handler.i != 0 {
+		t.Fatalf("expected 0 errors count, actual: %d", handler.i)
+	}
+}
+
+// stubbornTester finishes its test even when its context is cancelled
+type stubbornTester struct {
+	d time.Duration
+}
+
+func (s *stubbornTester) Test(_ context.Context) (core.Speed, error) {
+	time.Sleep(s.d)
+	return core.Speed{Download: 1.0, Upload: 1.0, Ping: 1 * time.Millisecond, Timestamp: time.Now()}, nil
+}
+
+// contextStorage fails pushes with done context, like storages sending requests do
+type contextStorage struct {
+	*inMemoryStorage
+}
+
+func (c *contextStorage) Push(ctx context.Context, speed core.Speed) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.inMemoryStorage.Push(ctx, speed)
+}
+
+type slowTester struct {
+	d time.Duration
+}
+
+func (s *slowTester) Test(ctx context.Context) (core.Speed, error) {
+	select {
+	case <-ctx.Done():
+		return core.InvalidSpeed, ctx.Err()
+	case <-time.After(s.d):
+		return core.Speed{Download: 1.0, Upload: 1.0, Ping: 1 * time.Millisecond, Timestamp: time.Now()}, nil
+	}
+}
+
+func Test_BootPassesJobNameToTester(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	storage := newInMemoryStorage()
+	scheduler := schedule.NewScheduler()
+	t.Cleanup(func() {
+		_ = scheduler.Close()
+	})
+	cfg := core.Config{Jobs: []core.Job{{Name: "fiber", Interval: time.Hour, Tester: &jobTester{}}}}
+
+	err := core.Boot(ctx, cfg, scheduler, nil, storage, &countingHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(storage.s) != 1 || storage.s[0].Tags["tested_by"] != "fiber" {
+		t.Fatalf("expected result of tester run by job: fiber, actual: %v", storage.s)
+	}
+}
+
+// jobTester tags the result with the name of the job from the context
+type jobTester struct{}
+
+func (j *jobTester) Test(ctx context.Context) (core.Speed, error) {
+	return core.Speed{Download: 1.0, Timestamp: time.Now()}.WithTag("tested_by", core.JobName(ctx)), nil
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const ProviderTag = "provider"
+
+// NamedTester is a SpeedTester of Composite. Name is used as a value of ProviderTag
+type NamedTester struct {
+	Name   string
+	Tester SpeedTester
+}
+
+func NewComposite(testers ...NamedTester) *Composite {
+	return &Composite{testers: testers}
+}
+
+// Composite runs several testers sequentially, so they never overlap and do not affect each other's results
+type Composite struct {
+	testers []NamedTester
+}
+
+// Test runs all the testers and returns result of the first one which succeeded. Use TestAll to get all the results
+func (c *Composite) Test(ctx context.Context) (Speed, error) {
+	speeds, errs := c.TestAll(ctx)
+	if len(speeds) > 0 {
+		return speeds[0], nil
+	}
+	if len(errs) > 0 {
+		return InvalidSpeed, errs[0]
+	}
+	return InvalidSpeed, errors.New("composite tester has no testers")
+}
+
+// TestAll runs all the testers, tagging every result with provider name. Errors are prefixed with provider name
+func (c *Composite) TestAll(ctx context.Context) ([]Speed, []error) {
+	speeds := make([]Speed, 0, len(c.testers))
+	errs := make([]error, 0)
+	for _, t := range c.testers {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, Classify(UnknownPhase, ctx.Err())))
+			continue
+		}
+		s, err := t.Tester.Test(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, Classify(UnknownPhase, err)))
+			continue
+		}
+		speeds = append(speeds, s.WithTag(ProviderTag, t.Name))
+	}
+	return speeds, errs
+}
+
+// TestAll runs tester and returns all of its results, e.g. one of every provider of Composite
+func TestAll(ctx context.Context, tester SpeedTester) ([]Speed, []error) {
+	if m, ok := tester.(MultiTester); ok {
+		return m.TestAll(ctx)
+	}
+	s, err := tester.Test(ctx)
+	if err != nil {
+		return nil, []error{Classify(UnknownPhase, err)}
+	}
+	return []Speed{s}, nil
+}
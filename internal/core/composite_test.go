@@ -0,0 +1,83 @@
+package core_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"strings"
+	"testing"
+)
+
+func TestComposite_TestAll(t *testing.T) {
+	t.Run("should run all testers and tag results with provider", func(t *testing.T) {
+		composite := core.NewComposite(
+			core.NamedTester{Name: "first", Tester: &dummyTester{}},
+			core.NamedTester{Name: "failing", Tester: &failingTester{}},
+			core.NamedTester{Name: "second", Tester: &dummyTester{}},
+		)
+
+		speeds, errs := composite.TestAll(context.Background())
+		if len(speeds) != 2 {
+			t.Fatalf("expected 2 results, actual: %d", len(speeds))
+		}
+		if speeds[0].Tags[core.ProviderTag] != "first" || speeds[1].Tags[core.ProviderTag] != "second" {
+			t.Fatalf("expected results tagged with provider, actual: %v", speeds)
+		}
+		if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "failing:") {
+			t.Fatalf("expected error of failing provider, actual: %v", errs)
+		}
+		if core.AsError(errs[0]) == nil {
+			t.Fatalf("expected classified error, actual: %v", errs[0])
+		}
+	})
+
+	t.Run("should not run testers when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		tester := &countingTester{}
+		composite := core.NewComposite(core.NamedTester{Name: "counting", Tester: tester})
+
+		speeds, errs := composite.TestAll(ctx)
+		if len(speeds) != 0 || len(errs) != 1 {
+			t.Fatalf("expected only cancellation error, actual speeds: %v, errors: %v", speeds, errs)
+		}
+		if tester.i != 0 {
+			t.Fatalf("expected tester not to be run, actual count: %d", tester.i)
+		}
+	})
+}
+
+func TestComposite_Test(t *testing.T) {
+	composite := core.NewComposite(
+		core.NamedTester{Name: "failing", Tester: &failingTester{}},
+		core.NamedTester{Name: "dummy", Tester: &dummyTester{}},
+	)
+
+	speed, err := composite.Test(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speed.Tags[core.ProviderTag] != "dummy" {
+		t.Fatalf("expected result of the first successful provider, actual: %v", speed)
+	}
+}
+
+func TestSpeed_WithTag(t *testing.T) {
+	original := core.Speed{Tags: map[string]string{"key": "value"}}
+	tagged := original.WithTag("other", "value")
+
+	if len(original.Tags) != 1 {
+		t.Fatalf("expected original tags not to be modified, actual: %v", original.Tags)
+	}
+	if len(tagged.Tags) != 2 {
+		t.Fatalf("expected 2 tags, actual: %v", tagged.Tags)
+	}
+}
+
+type countingTester struct {
+	i int
+}
+
+func (c *countingTester) Test(_ context.Context) (core.Speed, error) {
+	c.i++
+	return core.Speed{}, nil
+}
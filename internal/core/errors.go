@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Phase names the step of the measurement pipeline which failed
+type Phase string
+
+const (
+	UnknownPhase  Phase = "unknown"
+	PingPhase     Phase = "ping test"
+	DownloadPhase Phase = "download test"
+	UploadPhase   Phase = "upload test"
+	StoragePhase  Phase = "storage push"
+)
+
+// Kind is a class of the failure cause
+type Kind string
+
+const (
+	UnknownKind    Kind = "unknown"
+	TimeoutKind    Kind = "timeout"
+	CancelledKind  Kind = "cancelled"
+	DNSKind        Kind = "dns"
+	ConnectionKind Kind = "connection"
+)
+
+// Error is returned by speed testers and storages, so ErrorHandler can tell apart different failures with errors.As
+type Error struct {
+	Phase Phase
+	Kind  Kind
+	// Retryable is true when the same operation may succeed when repeated
+	Retryable bool
+	// NetworkDown is true when the failure was caused by missing connectivity
+	NetworkDown bool
+	Err         error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed (%s): %v", e.Phase, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify wraps err into *Error describing given phase. Returns nil when err is nil.
+// If err already wraps *Error, it is returned unchanged
+func Classify(phase Phase, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	classified := &Error{Phase: phase, Kind: UnknownKind, Err: err}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.Canceled):
+		classified.Kind = CancelledKind
+	case errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err):
+		classified.Kind = TimeoutKind
+		classified.Retryable = true
+	case errors.As(err, &dnsErr):
+		classified.Kind = DNSKind
+		classified.Retryable = true
+		classified.NetworkDown = !dnsErr.IsNotFound
+	case errors.As(err, &opErr):
+		classified.Kind = ConnectionKind
+		classified.Retryable = true
+		classified.NetworkDown = isNetworkDown(err)
+	}
+	return classified
+}
+
+// AsError returns *Error wrapped by err, or nil if there is none
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+func isNetworkDown(err error) bool {
+	return errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETDOWN)
+}
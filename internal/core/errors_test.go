@@ -0,0 +1,95 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]struct {
+		err                    error
+		kind                   core.Kind
+		retryable, networkDown bool
+	}{
+		"should classify cancelled context": {
+			err:  context.Canceled,
+			kind: core.CancelledKind,
+		},
+		"should classify exceeded deadline as retryable timeout": {
+			err:       fmt.Errorf("wrapped: %w", context.DeadlineExceeded),
+			kind:      core.TimeoutKind,
+			retryable: true,
+		},
+		"should classify unresolvable host as dns error": {
+			err:       &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true},
+			kind:      core.DNSKind,
+			retryable: true,
+		},
+		"should classify unreachable dns server as network down": {
+			err:         &net.DNSError{Err: "server misbehaving", Name: "example.com"},
+			kind:        core.DNSKind,
+			retryable:   true,
+			networkDown: true,
+		},
+		"should classify unreachable network as network down": {
+			err:         &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)},
+			kind:        core.ConnectionKind,
+			retryable:   true,
+			networkDown: true,
+		},
+		"should classify refused connection": {
+			err:       &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			kind:      core.ConnectionKind,
+			retryable: true,
+		},
+		"should not classify unknown error": {
+			err:  errors.New("test"),
+			kind: core.UnknownKind,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := core.Classify(core.DownloadPhase, tt.err)
+			var e *core.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected *core.Error, actual: %T", err)
+			}
+			if e.Phase != core.DownloadPhase {
+				t.Errorf("expected phase: %s, actual: %s", core.DownloadPhase, e.Phase)
+			}
+			if e.Kind != tt.kind {
+				t.Errorf("expected kind: %s, actual: %s", tt.kind, e.Kind)
+			}
+			if e.Retryable != tt.retryable {
+				t.Errorf("expected retryable: %t, actual: %t", tt.retryable, e.Retryable)
+			}
+			if e.NetworkDown != tt.networkDown {
+				t.Errorf("expected network down: %t, actual: %t", tt.networkDown, e.NetworkDown)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected classified error to wrap: %v", tt.err)
+			}
+		})
+	}
+
+	t.Run("should return nil for nil error", func(t *testing.T) {
+		if err := core.Classify(core.PingPhase, nil); err != nil {
+			t.Fatalf("expected nil, actual: %v", err)
+		}
+	})
+
+	t.Run("should not reclassify classified error", func(t *testing.T) {
+		classified := core.Classify(core.PingPhase, context.Canceled)
+		err := core.Classify(core.StoragePhase, fmt.Errorf("wrapped: %w", classified))
+		if e := core.AsError(err); e == nil || e.Phase != core.PingPhase {
+			t.Fatalf("expected error of ping phase, actual: %v", err)
+		}
+	})
+}
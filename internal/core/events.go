@@ -0,0 +1,144 @@
+package core
+
+import (
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Event is published on the Bus at steps of the measurement pipeline. Subscribers tell events apart with type switch
+type Event interface {
+	event()
+}
+
+// TestStarted is published when the job starts the speed test
+type TestStarted struct {
+	Job  string
+	Time time.Time
+}
+
+// TestFailed is published for every error returned by the tester of the job
+type TestFailed struct {
+	Job string
+	Err error
+}
+
+// PhaseStarted is published by speed testers reporting progress, ID correlates it with PhaseFinished.
+// Job is taken from the context of the test with JobName
+type PhaseStarted struct {
+	ID    uuid.UUID
+	Job   string
+	Phase Phase
+	Start time.Time
+}
+
+// PhaseFinished is published when the phase of the speed test ends. Err is nil if the phase succeeded
+type PhaseFinished struct {
+	ID       uuid.UUID
+	Job      string
+	Phase    Phase
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// ResultProduced is published for every speed measured by the job, before it is queued for storage
+type ResultProduced struct {
+	Job   string
+	Speed Speed
+}
+
+// PushSucceeded is published when storage accepted the result
+type PushSucceeded struct {
+	Job   string
+	Speed Speed
+}
+
+// PushFailed is published when storage rejected the result
+type PushFailed struct {
+	Job   string
+	Speed Speed
+	Err   error
+}
+
+// TickSkipped is published when the job did not run on time, because the previous run took longer than the interval
+type TickSkipped struct {
+	Job string
+	// Missed is the number of runs which did not happen
+	Missed int
+}
+
+func (TestStarted) event()    {}
+func (TestFailed) event()     {}
+func (PhaseStarted) event()   {}
+func (PhaseFinished) event()  {}
+func (ResultProduced) event() {}
+func (PushSucceeded) event()  {}
+func (PushFailed) event()     {}
+func (TickSkipped) event()    {}
+
+// Subscriber receives events published on the Bus
+type Subscriber interface {
+	Notify(e Event)
+}
+
+// SubscriberFunc allows to use ordinary function as Subscriber
+type SubscriberFunc func(e Event)
+
+func (f SubscriberFunc) Notify(e Event) {
+	f(e)
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Bus delivers events to subscribers synchronously, in order of subscription, so subscribers must not block.
+// Publishing on nil *Bus is no-op, so components can treat the bus as optional
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers []subscription
+}
+
+type subscription struct {
+	id         int
+	subscriber Subscriber
+}
+
+// Subscribe registers s for all events published afterwards. Returned function cancels the subscription
+func (b *Bus) Subscribe(s Subscriber) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers = append(b.subscribers, subscription{id: id, subscriber: s})
+	return func() {
+		b.unsubscribe(id)
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subscribers := make([]subscription, len(b.subscribers))
+	copy(subscribers, b.subscribers)
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		s.subscriber.Notify(e)
+	}
+}
+
+func (b *Bus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subscribers {
+		if s.id == id {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
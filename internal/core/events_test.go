@@ -0,0 +1,88 @@
+package core_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/schedule"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBus_Subscribe(t *testing.T) {
+	bus := core.NewBus()
+	first, second := &recordingSubscriber{}, &recordingSubscriber{}
+	bus.Subscribe(first)
+	unsubscribe := bus.Subscribe(second)
+
+	bus.Publish(core.TestStarted{Job: "first"})
+	unsubscribe()
+	bus.Publish(core.TestStarted{Job: "second"})
+
+	if len(first.events) != 2 {
+		t.Fatalf("expected 2 events, actual: %v", first.events)
+	}
+	if len(second.events) != 1 {
+		t.Fatalf("expected 1 event before unsubscribing, actual: %v", second.events)
+	}
+}
+
+func TestBus_PublishNil(t *testing.T) {
+	var bus *core.Bus
+	bus.Publish(core.TestStarted{Job: "nil"})
+}
+
+func Test_BootPublishesEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	bus := core.NewBus()
+	subscriber := &recordingSubscriber{}
+	bus.Subscribe(subscriber)
+	tester := core.NewComposite(
+		core.NamedTester{Name: "dummy", Tester: &dummyTester{}},
+		core.NamedTester{Name: "failing", Tester: &failingTester{}},
+	)
+
+	cfg := core.Config{SpeedTestInterval: 1 * time.Hour, Bus: bus}
+	err := core.Boot(ctx, cfg, schedule.NewScheduler(), tester, &failingStorage{}, &countingHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var started, failed, produced, pushFailed int
+	for _, e := range subscriber.get() {
+		switch e.(type) {
+		case core.TestStarted:
+			started++
+		case core.TestFailed:
+			failed++
+		case core.ResultProduced:
+			produced++
+		case core.PushFailed:
+			pushFailed++
+		case core.PushSucceeded:
+			t.Fatal("expected push to fail")
+		}
+	}
+	if started != 1 || failed != 1 || produced != 1 || pushFailed != 1 {
+		t.Fatalf("expected one event of every type, started: %d, failed: %d, produced: %d, push failed: %d",
+			started, failed, produced, pushFailed)
+	}
+}
+
+type recordingSubscriber struct {
+	mu     sync.Mutex
+	events []core.Event
+}
+
+func (s *recordingSubscriber) Notify(e core.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+}
+
+func (s *recordingSubscriber) get() []core.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultJobName is the scheduler key of the job running tester passed to Boot
+const DefaultJobName = "SpeedTest"
+
+// Job is a speed test scheduled independently of other jobs. Name is the key of the job in Scheduler, so it must be
+// unique. Tags are added to every result of the job
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Tester   SpeedTester
+	Tags     map[string]string
+	// Storage receives results of the job. Storage passed to Boot is used when nil
+	Storage Storage
+}
+
+func (j Job) tag(s Speed) Speed {
+	for k, v := range j.Tags {
+		s = s.WithTag(k, v)
+	}
+	return s
+}
+
+type jobKey struct{}
+
+// WithJob returns ctx of the speed test run by the job, so the tester can tell which job its events belong to
+func WithJob(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, jobKey{}, name)
+}
+
+// JobName returns name of the job running the speed test with ctx, or empty string outside of Boot
+func JobName(ctx context.Context) string {
+	name, _ := ctx.Value(jobKey{}).(string)
+	return name
+}
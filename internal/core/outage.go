@@ -0,0 +1,30 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Outage is a period of missing connectivity. End is zero while the outage is still ongoing
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (o Outage) Ongoing() bool {
+	return o.End.IsZero()
+}
+
+// Duration returns duration of the outage. Duration of ongoing outage is measured until now
+func (o Outage) Duration(now time.Time) time.Duration {
+	if o.Ongoing() {
+		return now.Sub(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// OutageStorage is implemented by storages which can record outages. Every outage is pushed twice - when it starts
+// and when it ends
+type OutageStorage interface {
+	PushOutage(ctx context.Context, outage Outage) error
+}
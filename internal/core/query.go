@@ -0,0 +1,12 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Queryable is implemented by storages which can read stored results back, e.g. to summarize them
+type Queryable interface {
+	// Query returns results with timestamp in range [from, to), ordered from the oldest
+	Query(ctx context.Context, from, to time.Time) ([]Speed, error)
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+)
+
+// OverflowPolicy decides what happens with a result when the queue of results waiting for storage is full
+type OverflowPolicy string
+
+const (
+	// Block makes the tester wait until there is room in the queue
+	Block OverflowPolicy = "block"
+	// DropOldest discards the oldest queued result to make room for the new one
+	DropOldest OverflowPolicy = "drop-oldest"
+	// DropNewest discards the new result
+	DropNewest OverflowPolicy = "drop-newest"
+)
+
+// QueueConfig configures the queue of results between testers and storages. Zero value is an unbuffered queue
+// blocking testers until storage accepts the result
+type QueueConfig struct {
+	Capacity int
+	// Overflow is Block when empty. DropOldest and DropNewest need Capacity of at least 1, smaller one is 1
+	Overflow OverflowPolicy
+}
+
+// DefaultQueueConfig buffers results of several tests, so tests are not delayed by a slow push to the storage
+var DefaultQueueConfig = QueueConfig{Capacity: 32, Overflow: Block}
+
+// QueueObserver is notified about the queue of results, e.g. to export its state as metrics
+type QueueObserver interface {
+	QueueDepth(depth int)
+	ResultDropped(s Speed)
+}
+
+type queue struct {
+	c        chan result
+	overflow OverflowPolicy
+	observer QueueObserver
+	logger   *logging.Logger
+}
+
+func newQueue(cfg QueueConfig, observer QueueObserver, logger *logging.Logger) *queue {
+	overflow := cfg.Overflow
+	if overflow == "" {
+		overflow = Block
+	}
+	capacity := cfg.Capacity
+	if overflow != Block && capacity < 1 {
+		capacity = 1
+	}
+	if observer == nil {
+		observer = noopObserver{}
+	}
+	return &queue{c: make(chan result, capacity), overflow: overflow, observer: observer, logger: logger}
+}
+
+// put adds r to the queue according to overflow policy. Boot consumes the queue until all tests finished, so blocking
+// put always returns, also when the tests were cancelled
+func (q *queue) put(r result) {
+	defer q.observer.QueueDepth(len(q.c))
+	switch q.overflow {
+	case DropNewest:
+		select {
+		case q.c <- r:
+		default:
+			q.drop(r)
+		}
+	case DropOldest:
+		for {
+			select {
+			case q.c <- r:
+				return
+			default:
+			}
+			select {
+			case old := <-q.c:
+				q.drop(old)
+			default:
+			}
+		}
+	default:
+		q.c <- r
+	}
+}
+
+// results returns channel of queued results. taken must be called after every received result
+func (q *queue) results() <-chan result {
+	return q.c
+}
+
+func (q *queue) taken() {
+	q.observer.QueueDepth(len(q.c))
+}
+
+func (q *queue) drop(r result) {
+	q.logger.Warn("result queue is full, dropping result", "job", r.job.Name, "policy", q.overflow,
+		"download", r.speed.Download, "upload", r.speed.Upload, "ping", r.speed.Ping, "timestamp", r.speed.Timestamp)
+	q.observer.ResultDropped(r.speed)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) QueueDepth(int) {}
+
+func (noopObserver) ResultDropped(Speed) {}
@@ -0,0 +1,197 @@
+package core_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/schedule"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueue_Overflow(t *testing.T) {
+	tests := map[string]struct {
+		overflow core.OverflowPolicy
+		// kept is a provider whose result must be stored despite the overflow
+		kept string
+	}{
+		"drop newest": {overflow: core.DropNewest, kept: "first"},
+		"drop oldest": {overflow: core.DropOldest, kept: "third"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			observer := newQueueObserver()
+			storage := &blockingStorage{inMemoryStorage: newInMemoryStorage(), release: observer.dropped}
+			cfg := core.Config{
+				SpeedTestInterval: 1 * time.Hour,
+				GracePeriod:       1 * time.Second,
+				Queue:             core.QueueConfig{Capacity: 1, Overflow: tt.overflow},
+				QueueObserver:     observer,
+			}
+
+			err := core.Boot(ctx, cfg, schedule.NewScheduler(), threeTesters(), storage, &countingHandler{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if observer.drops == 0 {
+				t.Fatal("expected dropped results")
+			}
+			if len(storage.s)+observer.drops != 3 {
+				t.Fatalf("expected every result to be stored or dropped, stored: %d, dropped: %d", len(storage.s), observer.drops)
+			}
+			if !storedBy(storage.s, tt.kept) {
+				t.Fatalf("expected result of %s to be stored, actual: %v", tt.kept, storage.s)
+			}
+			if observer.depth != 0 {
+				t.Fatalf("expected empty queue after shutdown, actual depth: %d", observer.depth)
+			}
+		})
+	}
+}
+
+func TestQueue_Block(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	observer := newQueueObserver()
+	storage := newInMemoryStorage()
+	cfg := core.Config{
+		SpeedTestInterval: 1 * time.Hour,
+		GracePeriod:       1 * time.Second,
+		Queue:             core.QueueConfig{Capacity: 1, Overflow: core.Block},
+		QueueObserver:     observer,
+	}
+
+	err := core.Boot(ctx, cfg, schedule.NewScheduler(), threeTesters(), storage, &countingHandler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if observer.drops != 0 {
+		t.Fatalf("expected no dropped results, actual: %d", observer.drops)
+	}
+	if len(storage.s) != 3 {
+		t.Fatalf("expected 3 stored results, actual: %d", len(storage.s))
+	}
+}
+
+func TestQueue_DefaultDoesNotDelayTests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage := &slowStorage{inMemoryStorage: newInMemoryStorage(), d: 100 * time.Millisecond}
+	scheduler := &burstScheduler{runs: 2, done: make(chan struct{})}
+	cfg := core.Config{SpeedTestInterval: time.Hour, GracePeriod: time.Second, Queue: core.DefaultQueueConfig}
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- core.Boot(ctx, cfg, scheduler, threeTesters(), storage, &countingHandler{})
+	}()
+	<-scheduler.done
+	cancel()
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+
+	if scheduler.took[1] > 50*time.Millisecond {
+		t.Fatalf("expected second test not to wait for the storage, took: %v", scheduler.took[1])
+	}
+	if len(storage.s) != 6 {
+		t.Fatalf("expected 6 stored results, actual: %d", len(storage.s))
+	}
+}
+
+func threeTesters() core.SpeedTester {
+	return core.NewComposite(
+		core.NamedTester{Name: "first", Tester: &dummyTester{}},
+		core.NamedTester{Name: "second", Tester: &dummyTester{}},
+		core.NamedTester{Name: "third", Tester: &dummyTester{}},
+	)
+}
+
+func storedBy(speeds []core.Speed, provider string) bool {
+	for _, s := range speeds {
+		if s.Tags[core.ProviderTag] == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// blockingStorage does not accept results until release is closed
+type blockingStorage struct {
+	*inMemoryStorage
+	release <-chan struct{}
+}
+
+func (s *blockingStorage) Push(ctx context.Context, speed core.Speed) error {
+	<-s.release
+	return s.inMemoryStorage.Push(ctx, speed)
+}
+
+// slowStorage accepts every result after d
+type slowStorage struct {
+	*inMemoryStorage
+	d time.Duration
+}
+
+func (s *slowStorage) Push(ctx context.Context, speed core.Speed) error {
+	time.Sleep(s.d)
+	return s.inMemoryStorage.Push(ctx, speed)
+}
+
+// burstScheduler runs the task given number of times one after another, measuring every run
+type burstScheduler struct {
+	runs int
+	took []time.Duration
+	done chan struct{}
+}
+
+func (b *burstScheduler) Schedule(_ context.Context, _ string, _ time.Duration, task func()) error {
+	go func() {
+		defer close(b.done)
+		for i := 0; i < b.runs; i++ {
+			start := time.Now()
+			task()
+			b.took = append(b.took, time.Since(start))
+		}
+	}()
+	return nil
+}
+
+func (b *burstScheduler) Cancel(string) error {
+	return nil
+}
+
+func (b *burstScheduler) Close() error {
+	return nil
+}
+
+func newQueueObserver() *queueObserver {
+	return &queueObserver{dropped: make(chan struct{})}
+}
+
+// queueObserver closes dropped channel when the first result is dropped
+type queueObserver struct {
+	mu      sync.Mutex
+	depth   int
+	drops   int
+	dropped chan struct{}
+}
+
+func (o *queueObserver) QueueDepth(depth int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.depth = depth
+}
+
+func (o *queueObserver) ResultDropped(core.Speed) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.drops == 0 {
+		close(o.dropped)
+	}
+	o.drops++
+}
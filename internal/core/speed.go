@@ -2,16 +2,30 @@ package core
 
 import (
 	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
 	"time"
 )
 
-var InvalidSpeed = Speed{-1, -1, -1, time.Unix(0, 0)}
+var InvalidSpeed = Speed{Download: -1, Upload: -1, Ping: -1, Timestamp: time.Unix(0, 0)}
 
 type Speed struct {
 	Download  float64
 	Upload    float64
 	Ping      time.Duration
 	Timestamp time.Time
+	// Tags describe the measurement, e.g. provider which made it. Storages should store them along the result
+	Tags map[string]string
+}
+
+// WithTag returns copy of the Speed with additional tag. Tags of the original Speed are not modified
+func (s Speed) WithTag(key, value string) Speed {
+	tags := make(map[string]string, len(s.Tags)+1)
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	tags[key] = value
+	s.Tags = tags
+	return s
 }
 
 type Storage interface {
@@ -23,6 +37,12 @@ type SpeedTester interface {
 	Test(context.Context) (Speed, error)
 }
 
+// MultiTester makes several measurements at once. Boot pushes every result of TestAll to the storage
+type MultiTester interface {
+	SpeedTester
+	TestAll(context.Context) ([]Speed, []error)
+}
+
 type Scheduler interface {
 	Schedule(ctx context.Context, key string, d time.Duration, task func()) error
 	Cancel(key string) error
@@ -35,4 +55,20 @@ type ErrorHandler interface {
 
 type Config struct {
 	SpeedTestInterval time.Duration
+	// Jobs are scheduled instead of the single job running tester passed to Boot every SpeedTestInterval
+	Jobs []Job
+	// GracePeriod is how long Boot waits for running speed tests after its context was cancelled. Zero means the tests
+	// are cancelled immediately
+	GracePeriod time.Duration
+	// PushTimeout limits every push to the storage. Pushes are not cancelled with the tests, so results of the tests
+	// finished during the shutdown are stored too. One minute is used when zero
+	PushTimeout time.Duration
+	// Queue buffers results, so slow storage does not stall speed tests
+	Queue QueueConfig
+	// QueueObserver is notified about depth of the queue and dropped results. Optional
+	QueueObserver QueueObserver
+	// Bus receives events of every job. Optional
+	Bus *Bus
+	// Logger of Boot, logging.Default is used when nil
+	Logger *logging.Logger
 }
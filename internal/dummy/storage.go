@@ -3,18 +3,26 @@ package dummy
 import (
 	"context"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"sort"
+	"sync"
+	"time"
 )
 
 func NewStorage() *Storage {
 	s := make([]core.Speed, 0)
-	return &Storage{s}
+	o := make([]core.Outage, 0)
+	return &Storage{s: s, o: o}
 }
 
 type Storage struct {
-	s []core.Speed
+	mu sync.Mutex
+	s  []core.Speed
+	o  []core.Outage
 }
 
 func (s *Storage) Push(_ context.Context, speed core.Speed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.s = append(s.s, speed)
 	return nil
 }
@@ -24,5 +32,35 @@ func (s *Storage) Close() error {
 }
 
 func (s *Storage) GetAll() []core.Speed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.s
 }
+
+func (s *Storage) Query(_ context.Context, from, to time.Time) ([]core.Speed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]core.Speed, 0)
+	for _, speed := range s.s {
+		if !speed.Timestamp.Before(from) && speed.Timestamp.Before(to) {
+			out = append(out, speed)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+	return out, nil
+}
+
+func (s *Storage) PushOutage(_ context.Context, outage core.Outage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.o = append(s.o, outage)
+	return nil
+}
+
+func (s *Storage) GetOutages() []core.Outage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.o
+}
@@ -2,9 +2,11 @@ package dummy_test
 
 import (
 	"context"
+	"github.com/google/go-cmp/cmp"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/dummy"
 	"testing"
+	"time"
 )
 
 func TestStorage(t *testing.T) {
@@ -19,8 +21,40 @@ func TestStorage(t *testing.T) {
 		if len(all) != 1 {
 			t.Fatalf("expected one element, actual: %d", len(all))
 		}
-		if all[0] != core.InvalidSpeed {
+		if !cmp.Equal(all[0], core.InvalidSpeed) {
 			t.Fatalf("expected speed: %v, actual: %v", core.InvalidSpeed, all[0])
 		}
 	})
+	t.Run("should store an outage", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		outage := core.Outage{Start: time.Unix(1, 0), End: time.Unix(2, 0)}
+		err := storage.PushOutage(context.Background(), outage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all := storage.GetOutages()
+		if len(all) != 1 {
+			t.Fatalf("expected one element, actual: %d", len(all))
+		}
+		if all[0] != outage {
+			t.Fatalf("expected outage: %v, actual: %v", outage, all[0])
+		}
+	})
+	t.Run("should query speeds in range", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		for _, sec := range []int64{3, 1, 2, 4} {
+			_ = storage.Push(context.Background(), core.Speed{Download: float64(sec), Timestamp: time.Unix(sec, 0)})
+		}
+		speeds, err := storage.Query(context.Background(), time.Unix(1, 0), time.Unix(4, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		downloads := make([]float64, 0, len(speeds))
+		for _, s := range speeds {
+			downloads = append(downloads, s.Download)
+		}
+		if !cmp.Equal(downloads, []float64{1, 2, 3}) {
+			t.Fatalf("expected speeds from [1, 4) ordered by time, actual: %v", downloads)
+		}
+	})
 }
@@ -0,0 +1,173 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/analytics"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"time"
+)
+
+// Period of the digest
+type Period string
+
+const (
+	Daily  Period = "daily"
+	Weekly Period = "weekly"
+)
+
+// DefaultDigestTemplates are rendered with Summary
+var DefaultDigestTemplates = Templates{
+	Subject: `[speedtest] {{.Period}} digest: {{time .From}} - {{time .To}}`,
+	Text: `Speed tests from {{time .From}} to {{time .To}}: {{.Count}}
+{{- if .Count}}
+               min      avg      max
+Download  {{printf "%8s %8s %8s" (mbps .Download.Min) (mbps .Download.Avg) (mbps .Download.Max)}}
+Upload    {{printf "%8s %8s %8s" (mbps .Upload.Min) (mbps .Upload.Avg) (mbps .Upload.Max)}}
+Ping [ms] {{printf "%8s %8s %8s" (ms .Ping.Min) (ms .Ping.Avg) (ms .Ping.Max)}}
+{{- end}}
+`,
+	HTML: `<html><body>
+<h2>Speed tests from {{time .From}} to {{time .To}}: {{.Count}}</h2>
+{{- if .Count}}
+<table>
+<tr><th></th><th>min</th><th>avg</th><th>max</th></tr>
+<tr><td>Download</td><td>{{mbps .Download.Min}}</td><td>{{mbps .Download.Avg}}</td><td>{{mbps .Download.Max}}</td></tr>
+<tr><td>Upload</td><td>{{mbps .Upload.Min}}</td><td>{{mbps .Upload.Avg}}</td><td>{{mbps .Upload.Max}}</td></tr>
+<tr><td>Ping [ms]</td><td>{{ms .Ping.Min}}</td><td>{{ms .Ping.Avg}}</td><td>{{ms .Ping.Max}}</td></tr>
+</table>
+{{- end}}
+</body></html>
+`,
+}
+
+type DigestCfg struct {
+	Period Period
+	// At is the time of the day, when the digest is sent, e.g. 8h
+	At time.Duration
+	// Weekday when the weekly digest is sent
+	Weekday time.Weekday
+	// Location of At. time.Local when nil
+	Location  *time.Location
+	Templates Templates
+}
+
+// Stats of the single metric
+type Stats struct {
+	Min, Avg, Max float64
+}
+
+// Summary of results in range [From, To). Ping is in milliseconds
+type Summary struct {
+	Period                 Period
+	From, To               time.Time
+	Count                  int
+	Download, Upload, Ping Stats
+}
+
+func NewDigest(cfg DigestCfg, storage core.Queryable, mailer *Mailer) (*Digest, error) {
+	switch cfg.Period {
+	case Daily, Weekly:
+	default:
+		return nil, fmt.Errorf("unsupported digest period: %s", cfg.Period)
+	}
+	if cfg.At < 0 || cfg.At >= 24*time.Hour {
+		return nil, fmt.Errorf("digest time of the day must be in range [0, 24h), actual: %v", cfg.At)
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	parsed, err := parseTemplates("digest", cfg.Templates, DefaultDigestTemplates)
+	if err != nil {
+		return nil, err
+	}
+	return &Digest{cfg: cfg, storage: storage, mailer: mailer, templates: parsed}, nil
+}
+
+// Digest periodically sends summary of results read from the storage
+type Digest struct {
+	cfg       DigestCfg
+	storage   core.Queryable
+	mailer    *Mailer
+	templates *templates
+}
+
+// Next returns when the digest should be sent after now
+func (d *Digest) Next(now time.Time) time.Time {
+	now = now.In(d.cfg.Location)
+	days := 0
+	if d.cfg.Period == Weekly {
+		days = (int(d.cfg.Weekday) - int(now.Weekday()) + 7) % 7
+	}
+	next := d.at(now, days)
+	if !next.After(now) {
+		if d.cfg.Period == Weekly {
+			days += 7
+		} else {
+			days++
+		}
+		next = d.at(now, days)
+	}
+	return next
+}
+
+func (d *Digest) at(now time.Time, days int) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, d.cfg.Location).Add(d.cfg.At)
+}
+
+// Send summarizes results of the last period before now and sends the digest
+func (d *Digest) Send(ctx context.Context, now time.Time) error {
+	from := now.AddDate(0, 0, -1)
+	if d.cfg.Period == Weekly {
+		from = now.AddDate(0, 0, -7)
+	}
+	speeds, err := d.storage.Query(ctx, from, now)
+	if err != nil {
+		return fmt.Errorf("could not query results for digest: %w", err)
+	}
+
+	summary := Summarize(speeds)
+	summary.Period, summary.From, summary.To = d.cfg.Period, from, now
+	msg, err := d.templates.render(summary)
+	if err != nil {
+		return err
+	}
+	return d.mailer.Send(ctx, msg)
+}
+
+// Run sends the digest on schedule until ctx is cancelled. Errors are passed to the error handler
+func (d *Digest) Run(ctx context.Context, errH core.ErrorHandler) {
+	for {
+		next := d.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := d.Send(ctx, next); err != nil {
+				errH.Handle(fmt.Errorf("could not send %s digest: %w", d.cfg.Period, err))
+			}
+		}
+	}
+}
+
+// Summarize returns min, avg and max of download, upload and ping. Period, From and To are not set
+func Summarize(speeds []core.Speed) Summary {
+	s := Summary{Count: len(speeds)}
+	if len(speeds) == 0 {
+		return s
+	}
+	s.Download, s.Upload, s.Ping = stats(speeds, analytics.Download), stats(speeds, analytics.Upload), stats(speeds, analytics.Ping)
+	return s
+}
+
+// stats of the metric, the minimum and the maximum are its lowest and highest percentiles
+func stats(speeds []core.Speed, m analytics.Metric) Stats {
+	values := make([]float64, 0, len(speeds))
+	for _, s := range speeds {
+		v, _ := m.Value(s)
+		values = append(values, v)
+	}
+	return Stats{Min: analytics.Percentile(values, 0), Avg: analytics.Average(values), Max: analytics.Percentile(values, 100)}
+}
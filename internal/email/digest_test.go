@@ -0,0 +1,151 @@
+package email_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/dummy"
+	"github.com/paluszkiewiczB/speedtest/internal/email"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := email.Summarize([]core.Speed{
+		{Download: 100, Upload: 10, Ping: 10 * time.Millisecond},
+		{Download: 300, Upload: 30, Ping: 30 * time.Millisecond},
+		{Download: 200, Upload: 50, Ping: 20 * time.Millisecond},
+	})
+
+	if summary.Count != 3 {
+		t.Fatalf("expected 3 results, actual: %d", summary.Count)
+	}
+	expected := map[string][2]email.Stats{
+		"download": {{Min: 100, Avg: 200, Max: 300}, summary.Download},
+		"upload":   {{Min: 10, Avg: 30, Max: 50}, summary.Upload},
+		"ping":     {{Min: 10, Avg: 20, Max: 30}, summary.Ping},
+	}
+	for metric, stats := range expected {
+		if stats[0] != stats[1] {
+			t.Fatalf("expected %s: %+v, actual: %+v", metric, stats[0], stats[1])
+		}
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	summary := email.Summarize(nil)
+	if summary != (email.Summary{}) {
+		t.Fatalf("expected empty summary, actual: %+v", summary)
+	}
+}
+
+func TestDigest_Next(t *testing.T) {
+	// 2022-05-04 is Wednesday
+	wednesday := func(hour, min int) time.Time {
+		return time.Date(2022, 5, 4, hour, min, 0, 0, time.UTC)
+	}
+	tests := map[string]struct {
+		cfg      email.DigestCfg
+		now      time.Time
+		expected time.Time
+	}{
+		"daily, later today": {
+			cfg:      email.DigestCfg{Period: email.Daily, At: 8 * time.Hour},
+			now:      wednesday(7, 59),
+			expected: wednesday(8, 0),
+		},
+		"daily, tomorrow": {
+			cfg:      email.DigestCfg{Period: email.Daily, At: 8 * time.Hour},
+			now:      wednesday(8, 0),
+			expected: time.Date(2022, 5, 5, 8, 0, 0, 0, time.UTC),
+		},
+		"weekly, later this week": {
+			cfg:      email.DigestCfg{Period: email.Weekly, At: 8 * time.Hour, Weekday: time.Friday},
+			now:      wednesday(12, 0),
+			expected: time.Date(2022, 5, 6, 8, 0, 0, 0, time.UTC),
+		},
+		"weekly, later today": {
+			cfg:      email.DigestCfg{Period: email.Weekly, At: 18 * time.Hour, Weekday: time.Wednesday},
+			now:      wednesday(12, 0),
+			expected: wednesday(18, 0),
+		},
+		"weekly, next week": {
+			cfg:      email.DigestCfg{Period: email.Weekly, At: 8 * time.Hour, Weekday: time.Monday},
+			now:      wednesday(12, 0),
+			expected: time.Date(2022, 5, 9, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.cfg.Location = time.UTC
+			digest, err := email.NewDigest(tt.cfg, dummy.NewStorage(), newMailer(t, email.Cfg{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next := digest.Next(tt.now)
+			if !next.Equal(tt.expected) {
+				t.Fatalf("expected: %v, actual: %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestDigest_Send(t *testing.T) {
+	stub := newSmtpStub(t)
+	storage := dummy.NewStorage()
+	now := time.Date(2022, 5, 4, 8, 0, 0, 0, time.UTC)
+	for _, s := range []core.Speed{
+		{Download: 999, Upload: 999, Ping: time.Second, Timestamp: now.Add(-25 * time.Hour)},
+		{Download: 100, Upload: 10, Ping: 10 * time.Millisecond, Timestamp: now.Add(-2 * time.Hour)},
+		{Download: 300, Upload: 30, Ping: 30 * time.Millisecond, Timestamp: now.Add(-time.Hour)},
+		{Download: 999, Upload: 999, Ping: time.Second, Timestamp: now},
+	} {
+		_ = storage.Push(context.Background(), s)
+	}
+	digest, err := email.NewDigest(
+		email.DigestCfg{Period: email.Daily, At: 8 * time.Hour, Location: time.UTC},
+		storage,
+		newMailer(t, email.Cfg{Port: stub.port()}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = digest.Send(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, parts := readMessage(t, only(t, stub).data)
+	if header.Get("Subject") != "[speedtest] daily digest: 2022-05-03 08:00 UTC - 2022-05-04 08:00 UTC" {
+		t.Fatalf("unexpected subject: %s", header.Get("Subject"))
+	}
+	text := parts["text/plain"]
+	for _, expected := range []string{": 2\n", "100.00   200.00   300.00", "Ping [ms]     10.0     20.0     30.0"} {
+		if !strings.Contains(text, expected) {
+			t.Fatalf("expected text to contain: %q, actual: %s", expected, text)
+		}
+	}
+	if strings.Contains(text, "999") {
+		t.Fatalf("expected results out of range to be skipped, actual: %s", text)
+	}
+}
+
+func TestNewDigest_Validation(t *testing.T) {
+	tests := map[string]email.DigestCfg{
+		"unsupported period":  {Period: "monthly"},
+		"time after midnight": {Period: email.Daily, At: 24 * time.Hour},
+		"invalid template":    {Period: email.Daily, Templates: email.Templates{Text: "{{.Count"}},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := email.NewDigest(cfg, dummy.NewStorage(), newMailer(t, email.Cfg{}))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Cfg struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN mechanism. Authentication is skipped when Username is empty
+	Username, Password string
+	From               string
+	To                 []string
+	// StartTLS upgrades the connection with STARTTLS. Sending fails when the server does not support it
+	StartTLS bool
+	// TLS is used by STARTTLS. ServerName is Host when nil
+	TLS *tls.Config
+	// Timeout limits the whole SMTP session. Zero means no limit
+	Timeout time.Duration
+}
+
+// Message is sent as multipart/alternative when both Text and HTML are set
+type Message struct {
+	Subject    string
+	Text, HTML string
+}
+
+func NewMailer(cfg Cfg) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if cfg.From == "" {
+		return nil, errors.New("sender of e-mails is required")
+	}
+	if len(cfg.To) == 0 {
+		return nil, errors.New("at least one recipient of e-mails is required")
+	}
+	if cfg.TLS == nil {
+		cfg.TLS = &tls.Config{ServerName: cfg.Host}
+	}
+	return &Mailer{cfg: cfg}, nil
+}
+
+// Mailer sends e-mails to all recipients. Every message is sent in a new SMTP session
+type Mailer struct {
+	cfg Cfg
+}
+
+func (m *Mailer) Send(ctx context.Context, msg Message) error {
+	body, err := m.compose(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("could not compose e-mail: %w", err)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not connect to smtp server: %s: %w", addr, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if m.cfg.Timeout > 0 && (!ok || time.Now().Add(m.cfg.Timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(m.cfg.Timeout), true
+	}
+	if ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+	return m.session(c, body)
+}
+
+func (m *Mailer) session(c *smtp.Client, body []byte) error {
+	if m.cfg.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(m.cfg.TLS); err != nil {
+			return fmt.Errorf("could not start TLS: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("could not authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range m.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient: %s was rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(body); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose encodes msg with all headers required by RFC 5322
+func (m *Mailer) compose(msg Message, now time.Time) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	header := func(k, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	header("From", m.cfg.From)
+	header("To", strings.Join(m.cfg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" || msg.Text == "" {
+		contentType, content := "text/plain", msg.Text
+		if msg.HTML != "" {
+			contentType, content = "text/html", msg.HTML
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuoted(buf, content); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuoted(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuoted(w io.Writer, content string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qw.Close()
+}
@@ -0,0 +1,197 @@
+package email_test
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/email"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMailer_Send(t *testing.T) {
+	stub := newSmtpStub(t)
+	mailer := newMailer(t, email.Cfg{Port: stub.port()})
+
+	err := mailer.Send(context.Background(), email.Message{
+		Subject: "Szybkość łącza",
+		Text:    "download: 300 Mbps",
+		HTML:    "<p>download: <b>300</b> Mbps</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := only(t, stub)
+	if m.from != "speedtest@example.com" || len(m.to) != 2 || m.to[1] != "ops@example.com" {
+		t.Fatalf("unexpected envelope: %v -> %v", m.from, m.to)
+	}
+	if m.secure || m.user != "" {
+		t.Fatalf("expected plain unauthenticated session, actual: %+v", m)
+	}
+	header, parts := readMessage(t, m.data)
+	if !strings.HasPrefix(header.Get("Content-Type"), "multipart/alternative") {
+		t.Fatalf("expected multipart/alternative, actual: %s", header.Get("Content-Type"))
+	}
+	if header.Get("Subject") != "=?utf-8?q?Szybko=C5=9B=C4=87_=C5=82=C4=85cza?=" {
+		t.Fatalf("expected encoded subject, actual: %s", header.Get("Subject"))
+	}
+	if parts["text/plain"] != "download: 300 Mbps" || parts["text/html"] != "<p>download: <b>300</b> Mbps</p>" {
+		t.Fatalf("unexpected parts: %v", parts)
+	}
+}
+
+func TestMailer_SendText(t *testing.T) {
+	stub := newSmtpStub(t)
+	mailer := newMailer(t, email.Cfg{Port: stub.port()})
+
+	err := mailer.Send(context.Background(), email.Message{Subject: "test", Text: "only text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, parts := readMessage(t, only(t, stub).data)
+	if header.Get("Content-Type") != "text/plain; charset=utf-8" || parts["text/plain"] != "only text" {
+		t.Fatalf("unexpected message: %v %v", header, parts)
+	}
+}
+
+func TestMailer_StartTLSAndAuth(t *testing.T) {
+	startTLS, pool := withStartTLS(t)
+	stub := newSmtpStub(t, startTLS, withAuth("user", "secret"))
+	mailer := newMailer(t, email.Cfg{
+		Port:     stub.port(),
+		Username: "user",
+		Password: "secret",
+		StartTLS: true,
+		TLS:      &tls.Config{ServerName: "127.0.0.1", RootCAs: pool},
+	})
+
+	err := mailer.Send(context.Background(), email.Message{Subject: "test", Text: "secure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := only(t, stub)
+	if !m.secure || m.user != "user" {
+		t.Fatalf("expected authenticated session over TLS, actual: %+v", m)
+	}
+}
+
+func TestMailer_Errors(t *testing.T) {
+	startTLS, _ := withStartTLS(t)
+	tests := map[string]struct {
+		stub *smtpStub
+		cfg  email.Cfg
+	}{
+		"STARTTLS not supported": {
+			stub: newSmtpStub(t),
+			cfg:  email.Cfg{StartTLS: true},
+		},
+		"untrusted certificate": {
+			stub: newSmtpStub(t, startTLS),
+			cfg:  email.Cfg{StartTLS: true},
+		},
+		"wrong password": {
+			stub: newSmtpStub(t, withAuth("user", "secret")),
+			cfg:  email.Cfg{Username: "user", Password: "wrong"},
+		},
+		"authentication required": {
+			stub: newSmtpStub(t, withAuth("user", "secret")),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.cfg.Port = tt.stub.port()
+			mailer := newMailer(t, tt.cfg)
+
+			err := mailer.Send(context.Background(), email.Message{Subject: "test", Text: "test"})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if len(tt.stub.received()) != 0 {
+				t.Fatalf("expected no e-mails, actual: %v", tt.stub.received())
+			}
+		})
+	}
+}
+
+func TestNewMailer_Validation(t *testing.T) {
+	tests := map[string]email.Cfg{
+		"no host":       {From: "a@example.com", To: []string{"b@example.com"}},
+		"no sender":     {Host: "localhost", To: []string{"b@example.com"}},
+		"no recipients": {Host: "localhost", From: "a@example.com"},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := email.NewMailer(cfg)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	stub := newSmtpStub(t)
+	notifier, err := email.NewNotifier(newMailer(t, email.Cfg{Port: stub.port()}), email.Templates{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(context.Background(), alert.Alert{
+		Rule:        "slow-download",
+		Job:         "home",
+		State:       alert.Firing,
+		Description: "download below 100 <Mbps>",
+		Value:       42,
+		StartsAt:    time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tags:        map[string]string{"isp": "acme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, parts := readMessage(t, only(t, stub).data)
+	if header.Get("Subject") != "[speedtest] firing: slow-download (home)" {
+		t.Fatalf("unexpected subject: %s", header.Get("Subject"))
+	}
+	for _, expected := range []string{"download below 100 <Mbps>", "Value: 42.00", "2022-05-01 12:00 UTC", "isp: acme"} {
+		if !strings.Contains(parts["text/plain"], expected) {
+			t.Fatalf("expected text to contain: %q, actual: %s", expected, parts["text/plain"])
+		}
+	}
+	if !strings.Contains(parts["text/html"], "download below 100 &lt;Mbps&gt;") {
+		t.Fatalf("expected escaped description in html, actual: %s", parts["text/html"])
+	}
+}
+
+func TestNewNotifier_InvalidTemplate(t *testing.T) {
+	_, err := email.NewNotifier(newMailer(t, email.Cfg{}), email.Templates{Subject: "{{.Rule"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func newMailer(t *testing.T, cfg email.Cfg) *email.Mailer {
+	cfg.Host = "127.0.0.1"
+	cfg.From = "speedtest@example.com"
+	cfg.To = []string{"admin@example.com", "ops@example.com"}
+	cfg.Timeout = 5 * time.Second
+	mailer, err := email.NewMailer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mailer
+}
+
+func only(t *testing.T, stub *smtpStub) mail {
+	received := stub.received()
+	if len(received) != 1 {
+		t.Fatalf("expected 1 e-mail, actual: %d", len(received))
+	}
+	return received[0]
+}
@@ -0,0 +1,59 @@
+package email
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+)
+
+// DefaultAlertTemplates are rendered with alert.Alert
+var DefaultAlertTemplates = Templates{
+	Subject: `[speedtest] {{.State}}: {{.Rule}} ({{.Job}})`,
+	Text: `Alert {{.Rule}} of job {{.Job}} is {{.State}}.
+{{.Description}}
+Value: {{printf "%.2f" .Value}}
+Started at: {{time .StartsAt}}
+{{- if not .EndsAt.IsZero}}
+Resolved at: {{time .EndsAt}}
+{{- end}}
+{{range $k, $v := .Tags}}
+{{$k}}: {{$v}}
+{{- end}}
+`,
+	HTML: `<html><body>
+<h2>Alert {{.Rule}} of job {{.Job}} is {{.State}}</h2>
+<p>{{.Description}}</p>
+<table>
+<tr><td>Value</td><td>{{printf "%.2f" .Value}}</td></tr>
+<tr><td>Started at</td><td>{{time .StartsAt}}</td></tr>
+{{- if not .EndsAt.IsZero}}
+<tr><td>Resolved at</td><td>{{time .EndsAt}}</td></tr>
+{{- end}}
+{{- range $k, $v := .Tags}}
+<tr><td>{{$k}}</td><td>{{$v}}</td></tr>
+{{- end}}
+</table>
+</body></html>
+`,
+}
+
+func NewNotifier(mailer *Mailer, t Templates) (*Notifier, error) {
+	parsed, err := parseTemplates("alert", t, DefaultAlertTemplates)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{mailer: mailer, templates: parsed}, nil
+}
+
+// Notifier sends alerts as e-mails
+type Notifier struct {
+	mailer    *Mailer
+	templates *templates
+}
+
+func (n *Notifier) Notify(ctx context.Context, a alert.Alert) error {
+	msg, err := n.templates.render(a)
+	if err != nil {
+		return err
+	}
+	return n.mailer.Send(ctx, msg)
+}
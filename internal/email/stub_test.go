@@ -0,0 +1,279 @@
+package email_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStub is a minimal in-process SMTP server which accepts every message
+type smtpStub struct {
+	ln net.Listener
+	// tls is advertised with STARTTLS, when not nil
+	tls *tls.Config
+	// username and password are required with AUTH PLAIN, when username is not empty
+	username, password string
+
+	mu    sync.Mutex
+	mails []mail
+}
+
+type mail struct {
+	from   string
+	to     []string
+	data   string
+	secure bool
+	user   string
+}
+
+type stubOpt func(s *smtpStub)
+
+// withStartTLS advertises STARTTLS with self-signed certificate. Returned pool trusts the certificate
+func withStartTLS(t *testing.T) (stubOpt, *x509.CertPool) {
+	cert, pool := selfSigned(t)
+	return func(s *smtpStub) {
+		s.tls = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}, pool
+}
+
+func withAuth(username, password string) stubOpt {
+	return func(s *smtpStub) {
+		s.username, s.password = username, password
+	}
+}
+
+func newSmtpStub(t *testing.T, opts ...stubOpt) *smtpStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln}
+	for _, opt := range opts {
+		opt(s)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) received() []mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mail(nil), s.mails...)
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tp := textproto.NewConn(conn)
+	reply := func(lines ...string) {
+		for _, l := range lines {
+			_ = tp.PrintfLine("%s", l)
+		}
+	}
+
+	current := mail{}
+	reply("220 stub ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i > 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"250-stub"}
+			if s.tls != nil && !current.secure {
+				lines = append(lines, "250-STARTTLS")
+			}
+			if s.username != "" {
+				lines = append(lines, "250-AUTH PLAIN")
+			}
+			reply(append(lines, "250 8BITMIME")...)
+		case "STARTTLS":
+			if s.tls == nil {
+				reply("502 not supported")
+				continue
+			}
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(tlsConn)
+			current.secure = true
+		case "AUTH":
+			user, ok := s.authenticate(arg)
+			if !ok {
+				reply("535 authentication failed")
+				continue
+			}
+			current.user = user
+			reply("235 authenticated")
+		case "MAIL":
+			if s.username != "" && current.user == "" {
+				reply("530 authentication required")
+				continue
+			}
+			current.from = address(arg)
+			reply("250 ok")
+		case "RCPT":
+			current.to = append(current.to, address(arg))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, current)
+			s.mu.Unlock()
+			current = mail{secure: current.secure, user: current.user}
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+// authenticate checks AUTH PLAIN with initial response
+func (s *smtpStub) authenticate(arg string) (string, bool) {
+	parts := strings.SplitN(arg, " ", 2)
+	if len(parts) != 2 || strings.ToUpper(parts[0]) != "PLAIN" {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Split(string(decoded), "\x00")
+	if len(fields) != 3 || fields[1] != s.username || fields[2] != s.password {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// address extracts address from FROM:<address> and TO:<address>
+func address(arg string) string {
+	start, end := strings.IndexByte(arg, '<'), strings.IndexByte(arg, '>')
+	if start < 0 || end < start {
+		return arg
+	}
+	return arg[start+1 : end]
+}
+
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// readMessage splits the e-mail into headers and bodies of parts, decoded from quoted-printable
+func readMessage(t *testing.T, data string) (textproto.MIMEHeader, map[string]string) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(data)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, decodeParts(t, header, r.R)
+}
+
+func decodeParts(t *testing.T, header textproto.MIMEHeader, body io.Reader) map[string]string {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string]string)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		// DATA of the session ends with new line, which is not a part of the body
+		parts[mediaType] = strings.TrimSuffix(decode(t, body), "\n")
+		return parts
+	}
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		partType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[partType] = decode(t, p)
+	}
+}
+
+func decode(t *testing.T, r io.Reader) string {
+	b, err := ioutil.ReadAll(quotedprintable.NewReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
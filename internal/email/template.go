@@ -0,0 +1,78 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"text/template"
+	"time"
+)
+
+// Templates of the message. Subject and Text are text/template sources, HTML is html/template source.
+// Empty template is replaced with the default one of the message
+type Templates struct {
+	Subject, Text, HTML string
+}
+
+type templates struct {
+	subject, text *template.Template
+	html          *htmlTemplate.Template
+}
+
+var funcs = map[string]interface{}{
+	"ms":   ms,
+	"mbps": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+}
+
+// ms formats time.Duration or float64, which is already in milliseconds like ping of Summary
+func ms(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case time.Duration:
+		return fmt.Sprintf("%.1f", float64(v)/float64(time.Millisecond)), nil
+	case float64:
+		return fmt.Sprintf("%.1f", v), nil
+	default:
+		return "", fmt.Errorf("ms expects duration or number of milliseconds, actual: %T", v)
+	}
+}
+
+func parseTemplates(name string, t, defaults Templates) (*templates, error) {
+	if t.Subject == "" {
+		t.Subject = defaults.Subject
+	}
+	if t.Text == "" {
+		t.Text = defaults.Text
+	}
+	if t.HTML == "" {
+		t.HTML = defaults.HTML
+	}
+
+	subject, err := template.New(name + "-subject").Funcs(funcs).Parse(t.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subject template of %s: %w", name, err)
+	}
+	text, err := template.New(name + "-text").Funcs(funcs).Parse(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse text template of %s: %w", name, err)
+	}
+	html, err := htmlTemplate.New(name + "-html").Funcs(funcs).Parse(t.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse html template of %s: %w", name, err)
+	}
+	return &templates{subject: subject, text: text, html: html}, nil
+}
+
+func (t *templates) render(data interface{}) (Message, error) {
+	subject, text, html := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	if err := t.subject.Execute(subject, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.Execute(text, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.Execute(html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: subject.String(), Text: text.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,61 @@
+package errHandlers
+
+import (
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"sync"
+	"time"
+)
+
+// Dedup passes error to the delegate once per window. Repeated errors with the same message are collapsed:
+// when the window ends, their count is passed to the delegate wrapping the last of them
+func Dedup(delegate core.ErrorHandler, window time.Duration) *DedupHandler {
+	return &DedupHandler{delegate: delegate, window: window, seen: make(map[string]*occurrence)}
+}
+
+type DedupHandler struct {
+	delegate core.ErrorHandler
+	window   time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*occurrence
+}
+
+// occurrence of the error passed to the delegate at since, followed by suppressed identical errors
+type occurrence struct {
+	since      time.Time
+	suppressed int
+	last       error
+}
+
+func (h *DedupHandler) Handle(err error) {
+	if err = h.collapse(err); err != nil {
+		h.delegate.Handle(err)
+	}
+}
+
+// collapse returns nil when err should be suppressed or err to pass to the delegate
+func (h *DedupHandler) collapse(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	msg := err.Error()
+	if o, ok := h.seen[msg]; ok {
+		o.suppressed++
+		o.last = err
+		return nil
+	}
+	h.seen[msg] = &occurrence{since: time.Now()}
+	time.AfterFunc(h.window, func() { h.end(msg) })
+	return err
+}
+
+// end finishes the window of the error and passes the number of its repeats to the delegate
+func (h *DedupHandler) end(msg string) {
+	h.mu.Lock()
+	o := h.seen[msg]
+	delete(h.seen, msg)
+	h.mu.Unlock()
+	if o.suppressed > 0 {
+		h.delegate.Handle(fmt.Errorf("%w (repeated %d times since %s)", o.last, o.suppressed, o.since.Format(time.RFC3339)))
+	}
+}
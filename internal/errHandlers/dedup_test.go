@@ -0,0 +1,82 @@
+package errHandlers_test
+
+import (
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_DedupCollapsesRepeatedErrors(t *testing.T) {
+	handled := &recordingHandler{}
+	handler := errHandlers.Dedup(handled, time.Hour)
+
+	handler.Handle(errors.New("storage is down"))
+	handler.Handle(errors.New("storage is down"))
+	handler.Handle(errors.New("tester failed"))
+	handler.Handle(errors.New("storage is down"))
+
+	errs := handled.handled()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 handled errors, got: %v", errs)
+	}
+	if errs[0].Error() != "storage is down" || errs[1].Error() != "tester failed" {
+		t.Fatalf("Expected first occurrences of errors, got: %v", errs)
+	}
+}
+
+func Test_DedupReportsRepeatsAfterWindow(t *testing.T) {
+	handled := &recordingHandler{}
+	handler := errHandlers.Dedup(handled, 20*time.Millisecond)
+	cause := errors.New("storage is down")
+
+	handler.Handle(cause)
+	handler.Handle(cause)
+	handler.Handle(cause)
+	time.Sleep(30 * time.Millisecond)
+
+	errs := handled.handled()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 handled errors, got: %v", errs)
+	}
+	if !strings.Contains(errs[1].Error(), "repeated 2 times") || !errors.Is(errs[1], cause) {
+		t.Fatalf("Expected error wrapping the cause with number of repeats, got: %v", errs[1])
+	}
+
+	handler.Handle(cause)
+	if errs := handled.handled(); len(errs) != 3 || errs[2] != cause {
+		t.Fatalf("Expected error passed again after the window, got: %v", errs)
+	}
+}
+
+func Test_DedupDoesNotReportWithoutRepeats(t *testing.T) {
+	handled := &recordingHandler{}
+	handler := errHandlers.Dedup(handled, 10*time.Millisecond)
+
+	handler.Handle(errors.New("storage is down"))
+	time.Sleep(20 * time.Millisecond)
+
+	if errs := handled.handled(); len(errs) != 1 {
+		t.Fatalf("Expected only the first error, got: %v", errs)
+	}
+}
+
+// recordingHandler is safe for concurrent use, because Dedup reports repeats from its timers
+type recordingHandler struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (h *recordingHandler) Handle(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err)
+}
+
+func (h *recordingHandler) handled() []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]error(nil), h.errs...)
+}
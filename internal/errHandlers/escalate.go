@@ -0,0 +1,48 @@
+package errHandlers
+
+import (
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"sync"
+)
+
+// Escalate calls exit after the given number of consecutive failed speed tests and pushes. Failures stop being
+// consecutive when a result is pushed to the storage. Other errors, e.g. of notifiers, are not counted. Escalation
+// must be subscribed to core.Bus of Boot
+func Escalate(after int, exit func(err error)) *Escalation {
+	return &Escalation{after: after, exit: exit}
+}
+
+type Escalation struct {
+	after int
+	exit  func(err error)
+
+	mu          sync.Mutex
+	consecutive int
+}
+
+// Notify counts failures and resets their number when a result is pushed
+func (e *Escalation) Notify(event core.Event) {
+	var err error
+	switch ev := event.(type) {
+	case core.PushSucceeded:
+		e.mu.Lock()
+		e.consecutive = 0
+		e.mu.Unlock()
+		return
+	case core.TestFailed:
+		err = fmt.Errorf("%s: %w", ev.Job, ev.Err)
+	case core.PushFailed:
+		err = fmt.Errorf("%s: %w", ev.Job, ev.Err)
+	default:
+		return
+	}
+
+	e.mu.Lock()
+	e.consecutive++
+	escalate := e.consecutive == e.after
+	e.mu.Unlock()
+	if escalate {
+		e.exit(fmt.Errorf("%d consecutive errors, the last one: %w", e.after, err))
+	}
+}
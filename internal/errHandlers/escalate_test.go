@@ -0,0 +1,39 @@
+package errHandlers_test
+
+import (
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"testing"
+)
+
+func Test_EscalateAfterConsecutiveErrors(t *testing.T) {
+	var exited []error
+	escalation := errHandlers.Escalate(3, func(err error) { exited = append(exited, err) })
+
+	escalation.Notify(core.TestFailed{Job: "SpeedTest", Err: errors.New("first")})
+	escalation.Notify(core.PushFailed{Job: "SpeedTest", Err: errors.New("second")})
+	escalation.Notify(core.PushSucceeded{Job: "SpeedTest"})
+	escalation.Notify(core.TestFailed{Job: "SpeedTest", Err: errors.New("third")})
+	escalation.Notify(core.TestFailed{Job: "SpeedTest", Err: errors.New("fourth")})
+	if len(exited) != 0 {
+		t.Fatalf("Expected no escalation after successful push, got: %v", exited)
+	}
+
+	escalation.Notify(core.PushFailed{Job: "SpeedTest", Err: errors.New("fifth")})
+	if len(exited) != 1 || exited[0].Error() != "3 consecutive errors, the last one: SpeedTest: fifth" {
+		t.Fatalf("Expected single escalation, got: %v", exited)
+	}
+}
+
+func Test_EscalateIgnoresOtherEvents(t *testing.T) {
+	var exited []error
+	escalation := errHandlers.Escalate(1, func(err error) { exited = append(exited, err) })
+
+	escalation.Notify(core.TestStarted{Job: "SpeedTest"})
+	escalation.Notify(core.ResultProduced{Job: "SpeedTest"})
+	escalation.Notify(core.TickSkipped{Job: "SpeedTest", Missed: 1})
+	if len(exited) != 0 {
+		t.Fatalf("Expected no escalation without failures, got: %v", exited)
+	}
+}
@@ -0,0 +1,18 @@
+package errHandlers
+
+import "github.com/paluszkiewiczB/speedtest/internal/core"
+
+// FanOut passes every error to all handlers, in given order
+func FanOut(handlers ...core.ErrorHandler) *FanOutHandler {
+	return &FanOutHandler{handlers: handlers}
+}
+
+type FanOutHandler struct {
+	handlers []core.ErrorHandler
+}
+
+func (h *FanOutHandler) Handle(err error) {
+	for _, d := range h.handlers {
+		d.Handle(err)
+	}
+}
@@ -0,0 +1,17 @@
+package errHandlers_test
+
+import (
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"testing"
+)
+
+func Test_FanOut(t *testing.T) {
+	first, second := &countingLogger{}, &countingLogger{}
+	handler := errHandlers.FanOut(errHandlers.New(first), errHandlers.New(second))
+	handler.Handle(errors.New("test"))
+
+	if first.i != 1 || second.i != 1 {
+		t.Fatalf("Expected err logged by both handlers, got: %d and %d", first.i, second.i)
+	}
+}
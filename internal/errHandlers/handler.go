@@ -1,6 +1,10 @@
 package errHandlers
 
-import "log"
+import (
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"log"
+)
 
 func NewPrintln() *Handler {
 	l := &funcDelegatingHandler{d: log.Println}
@@ -19,10 +23,19 @@ type Handler struct {
 	l Logger
 }
 
+// Handle logs err. Errors wrapping *core.Error are prefixed with their classification
 func (h Handler) Handle(err error) {
+	if e := core.AsError(err); e != nil {
+		h.l.Log(describe(e), err)
+		return
+	}
 	h.l.Log(err)
 }
 
+func describe(e *core.Error) string {
+	return fmt.Sprintf("[phase: %s, kind: %s, retryable: %t, network down: %t]", e.Phase, e.Kind, e.Retryable, e.NetworkDown)
+}
+
 type Logger interface {
 	Log(v ...interface{})
 }
@@ -2,8 +2,11 @@ package errHandlers_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/errHandlers"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
 	"log"
 	"os"
 	"strings"
@@ -57,3 +60,32 @@ func captureOutput(f func()) string {
 	log.SetOutput(os.Stderr)
 	return buf.String()
 }
+
+func Test_HandleClassified(t *testing.T) {
+	output := captureOutput(func() {
+		handler := errHandlers.NewPrintln()
+		handler.Handle(core.Classify(core.UploadPhase, context.DeadlineExceeded))
+	})
+
+	for _, want := range []string{"phase: upload test", "kind: timeout", "retryable: true", "network down: false"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected log containing: '%s', got: '%s'", want, output)
+		}
+	}
+}
+
+func Test_NewStructured(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger, err := logging.New(logging.Cfg{Output: buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := errHandlers.NewStructured(logger)
+
+	handler.Handle(core.Classify(core.StoragePhase, context.DeadlineExceeded))
+
+	expected := `level=error logger=errors msg=error err="storage push failed (timeout): context deadline exceeded" phase="storage push" kind=timeout retryable=true network_down=false`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("Expected record containing: %s, got: %s", expected, buf.String())
+	}
+}
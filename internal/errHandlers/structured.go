@@ -0,0 +1,24 @@
+package errHandlers
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+)
+
+// NewStructured logs errors at Error level. Classification of errors wrapping *core.Error is logged as fields
+func NewStructured(logger *logging.Logger) *StructuredHandler {
+	return &StructuredHandler{logger: logging.For(logger, "errors")}
+}
+
+type StructuredHandler struct {
+	logger *logging.Logger
+}
+
+func (h *StructuredHandler) Handle(err error) {
+	if e := core.AsError(err); e != nil {
+		h.logger.Error("error", "err", err, "phase", e.Phase, "kind", e.Kind, "retryable", e.Retryable,
+			"network_down", e.NetworkDown)
+		return
+	}
+	h.logger.Error("error", "err", err)
+}
@@ -0,0 +1,183 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status of the check or the whole report
+type Status string
+
+const (
+	Up   Status = "up"
+	Down Status = "down"
+)
+
+const (
+	SchedulerCheck = "scheduler"
+	TestCheck      = "last_test"
+	PushCheck      = "last_push"
+	StorageCheck   = "storage"
+)
+
+// Scheduler reports scheduled tasks, e.g. schedule.Scheduler. No tasks means that it is closed or nothing runs
+type Scheduler interface {
+	Tasks() []string
+}
+
+// Pinger checks connection to the storage, e.g. influx.Client
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Cfg struct {
+	// MaxTestAge is the longest time without a successful speed test before readiness fails. Zero disables the check
+	MaxTestAge time.Duration
+	// MaxPushAge is the longest time without a successful push before readiness fails. Zero disables the check
+	MaxPushAge time.Duration
+	// PingTimeout limits Ping of the storage, 5 seconds are used when zero
+	PingTimeout time.Duration
+}
+
+// NewChecker creates checker of the pipeline. Storage is not pinged when nil. Checker must be subscribed to the bus
+// to see results and pushes. Until the first of them, their age is counted since the checker was created
+func NewChecker(cfg Cfg, scheduler Scheduler, storage Pinger) *Checker {
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = 5 * time.Second
+	}
+	return &Checker{cfg: cfg, scheduler: scheduler, storage: storage, started: time.Now()}
+}
+
+type Checker struct {
+	cfg       Cfg
+	scheduler Scheduler
+	storage   Pinger
+	started   time.Time
+
+	mu                 sync.Mutex
+	lastTest, lastPush time.Time
+}
+
+func (c *Checker) Notify(e core.Event) {
+	switch e.(type) {
+	case core.ResultProduced:
+		c.mu.Lock()
+		c.lastTest = time.Now()
+		c.mu.Unlock()
+	case core.PushSucceeded:
+		c.mu.Lock()
+		c.lastPush = time.Now()
+		c.mu.Unlock()
+	}
+}
+
+// Report is the result of all checks. It is Down when any of the checks is Down
+type Report struct {
+	Status Status           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+type Check struct {
+	Status Status `json:"status"`
+	// Tasks of the scheduler
+	Tasks []string `json:"tasks,omitempty"`
+	// Last successful test or push, omitted when there was none
+	Last *time.Time `json:"last,omitempty"`
+	// Age since the last success, or since the start when there was none
+	Age string `json:"age,omitempty"`
+	// MaxAge is the threshold of the age, omitted when the age is not checked
+	MaxAge string `json:"max_age,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Liveness checks the scheduler. When it fails, the process should be restarted. Age of the last successful speed
+// test is reported, but not checked: tests fail during network outages and restarting the process does not help
+func (c *Checker) Liveness() Report {
+	return report(map[string]Check{
+		SchedulerCheck: c.checkScheduler(),
+		TestCheck:      c.checkAge(c.last(&c.lastTest), 0),
+	})
+}
+
+// Readiness checks the pipeline up to the storage: the scheduler, the age of the last successful speed test and
+// push and the connection to the storage
+func (c *Checker) Readiness(ctx context.Context) Report {
+	checks := map[string]Check{
+		SchedulerCheck: c.checkScheduler(),
+		TestCheck:      c.checkAge(c.last(&c.lastTest), c.cfg.MaxTestAge),
+		PushCheck:      c.checkAge(c.last(&c.lastPush), c.cfg.MaxPushAge),
+	}
+	if c.storage != nil {
+		checks[StorageCheck] = c.checkStorage(ctx)
+	}
+	return report(checks)
+}
+
+func (c *Checker) last(t *time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *t
+}
+
+func (c *Checker) checkScheduler() Check {
+	tasks := c.scheduler.Tasks()
+	if len(tasks) == 0 {
+		return Check{Status: Down, Error: "no scheduled tasks"}
+	}
+	return Check{Status: Up, Tasks: tasks}
+}
+
+func (c *Checker) checkAge(last time.Time, max time.Duration) Check {
+	since := last
+	check := Check{Status: Up}
+	if last.IsZero() {
+		since = c.started
+	} else {
+		check.Last = &last
+	}
+	age := time.Since(since)
+	check.Age = age.Round(time.Second).String()
+	if max > 0 {
+		check.MaxAge = max.String()
+		if age > max {
+			check.Status = Down
+		}
+	}
+	return check
+}
+
+func (c *Checker) checkStorage(ctx context.Context) Check {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.PingTimeout)
+	defer cancel()
+	if err := c.storage.Ping(ctx); err != nil {
+		return Check{Status: Down, Error: err.Error()}
+	}
+	return Check{Status: Up}
+}
+
+func report(checks map[string]Check) Report {
+	r := Report{Status: Up, Checks: checks}
+	for _, check := range checks {
+		if check.Status == Down {
+			r.Status = Down
+		}
+	}
+	return r
+}
+
+// Handler serves the report as JSON. Status of the response is 200 when the report is Up and 503 otherwise
+func Handler(check func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := check(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == Down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,190 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/health"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecker_Liveness(t *testing.T) {
+	tests := map[string]struct {
+		tasks []string
+		wait  time.Duration
+		want  map[string]health.Status
+	}{
+		"should be up after start": {
+			tasks: []string{"default"},
+			want:  map[string]health.Status{health.SchedulerCheck: health.Up, health.TestCheck: health.Up},
+		},
+		"should be down without scheduled tasks": {
+			want: map[string]health.Status{health.SchedulerCheck: health.Down, health.TestCheck: health.Up},
+		},
+		"should be up without tests for longer than max test age": {
+			tasks: []string{"default"},
+			wait:  20 * time.Millisecond,
+			want:  map[string]health.Status{health.SchedulerCheck: health.Up, health.TestCheck: health.Up},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := health.NewChecker(health.Cfg{MaxTestAge: 10 * time.Millisecond}, scheduler(tt.tasks), nil)
+			time.Sleep(tt.wait)
+
+			r := checker.Liveness()
+			assertReport(t, r, tt.want)
+			if r.Checks[health.TestCheck].MaxAge != "" {
+				t.Fatalf("expected age of the test without threshold, actual: %s", r.Checks[health.TestCheck].MaxAge)
+			}
+		})
+	}
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	t.Run("should be down when storage is not reachable", func(t *testing.T) {
+		checker := health.NewChecker(health.Cfg{}, scheduler{"default"}, pinger{err: errors.New("connection refused")})
+
+		r := checker.Readiness(context.Background())
+		assertReport(t, r, map[string]health.Status{
+			health.SchedulerCheck: health.Up,
+			health.TestCheck:      health.Up,
+			health.PushCheck:      health.Up,
+			health.StorageCheck:   health.Down,
+		})
+		if r.Checks[health.StorageCheck].Error != "connection refused" {
+			t.Fatalf("expected error of the ping, actual: %s", r.Checks[health.StorageCheck].Error)
+		}
+	})
+
+	t.Run("should check age of the last test", func(t *testing.T) {
+		tests := map[string]struct {
+			maxTestAge time.Duration
+			events     []core.Event
+			want       health.Status
+		}{
+			"should be down without tests since start": {maxTestAge: 10 * time.Millisecond, want: health.Down},
+			"should be up when result was produced recently": {
+				maxTestAge: 10 * time.Millisecond,
+				events:     []core.Event{core.ResultProduced{Job: "default"}},
+				want:       health.Up,
+			},
+			"should not check age without threshold": {want: health.Up},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				checker := health.NewChecker(health.Cfg{MaxTestAge: tt.maxTestAge}, scheduler{"default"}, nil)
+				time.Sleep(20 * time.Millisecond)
+				for _, e := range tt.events {
+					checker.Notify(e)
+				}
+
+				assertReport(t, checker.Readiness(context.Background()), map[string]health.Status{
+					health.SchedulerCheck: health.Up,
+					health.TestCheck:      tt.want,
+					health.PushCheck:      health.Up,
+				})
+			})
+		}
+	})
+
+	t.Run("should be down when push is stale", func(t *testing.T) {
+		checker := health.NewChecker(health.Cfg{MaxPushAge: 10 * time.Millisecond}, scheduler{"default"}, pinger{})
+		time.Sleep(20 * time.Millisecond)
+		checker.Notify(core.ResultProduced{Job: "default"})
+
+		assertReport(t, checker.Readiness(context.Background()), map[string]health.Status{
+			health.SchedulerCheck: health.Up,
+			health.TestCheck:      health.Up,
+			health.PushCheck:      health.Down,
+			health.StorageCheck:   health.Up,
+		})
+	})
+
+	t.Run("should report time of the last push", func(t *testing.T) {
+		checker := health.NewChecker(health.Cfg{MaxPushAge: time.Hour}, scheduler{"default"}, nil)
+		checker.Notify(core.PushSucceeded{Job: "default"})
+
+		r := checker.Readiness(context.Background())
+		if r.Status != health.Up || r.Checks[health.PushCheck].Last == nil {
+			t.Fatalf("expected recent push, actual: %+v", r.Checks[health.PushCheck])
+		}
+		if _, ok := r.Checks[health.StorageCheck]; ok {
+			t.Fatal("expected no storage check without pinger")
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	tests := map[string]struct {
+		report     health.Report
+		wantStatus int
+	}{
+		"should return 200 when up": {
+			report:     health.Report{Status: health.Up},
+			wantStatus: http.StatusOK,
+		},
+		"should return 503 when down": {
+			report:     health.Report{Status: health.Down},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := health.Handler(func(context.Context) health.Report { return tt.report })
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, actual: %d", tt.wantStatus, w.Code)
+			}
+			var got health.Report
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.report.Status {
+				t.Fatalf("expected report %s, actual: %s", tt.report.Status, got.Status)
+			}
+		})
+	}
+}
+
+func assertReport(t *testing.T, r health.Report, want map[string]health.Status) {
+	t.Helper()
+	if len(r.Checks) != len(want) {
+		t.Fatalf("expected checks: %v, actual: %+v", want, r.Checks)
+	}
+	status := health.Up
+	for name, s := range want {
+		if r.Checks[name].Status != s {
+			t.Errorf("expected %s to be %s, actual: %+v", name, s, r.Checks[name])
+		}
+		if s == health.Down {
+			status = health.Down
+		}
+	}
+	if r.Status != status {
+		t.Errorf("expected report to be %s, actual: %s", status, r.Status)
+	}
+}
+
+type scheduler []string
+
+func (s scheduler) Tasks() []string {
+	return s
+}
+
+type pinger struct {
+	err error
+}
+
+func (p pinger) Ping(context.Context) error {
+	return p.err
+}
@@ -0,0 +1,107 @@
+package httpspeed
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// counter counts bytes written by all the streams
+type counter struct {
+	n int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&c.n, int64(len(p)))
+	return len(p), nil
+}
+
+func (c *counter) load() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+type countingReader struct {
+	r io.Reader
+	c *counter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	_, _ = r.c.Write(p[:n])
+	return n, err
+}
+
+type counterKey struct{}
+
+func withCounter(ctx context.Context, c *counter) context.Context {
+	return context.WithValue(ctx, counterKey{}, c)
+}
+
+// uploadClient returns copy of the client, which counts bytes written to connections dialed for requests with
+// counter in the context. Keep-alives are disabled, so every request dials the connection with its own context.
+// It returns nil when transport of the client cannot be cloned
+func uploadClient(client *http.Client) *http.Client {
+	var transport *http.Transport
+	switch rt := client.Transport.(type) {
+	case nil:
+		def, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return nil
+		}
+		transport = def.Clone()
+	case *http.Transport:
+		transport = rt.Clone()
+	default:
+		return nil
+	}
+	transport.DisableKeepAlives = true
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = countingDial(dial)
+	if transport.DialTLSContext != nil {
+		transport.DialTLSContext = countingDial(transport.DialTLSContext)
+	}
+	c := *client
+	c.Transport = transport
+	return &c
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func countingDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := ctx.Value(counterKey{}).(*counter); ok {
+			return &countingConn{Conn: conn, c: c}, nil
+		}
+		return conn, nil
+	}
+}
+
+// countingConn counts bytes written to the connection
+type countingConn struct {
+	net.Conn
+	c *counter
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	_, _ = c.c.Write(p[:n])
+	return n, err
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,84 @@
+package httpspeed
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DownloadPath = "/download"
+	UploadPath   = "/upload"
+	PingPath     = "/ping"
+)
+
+type ServerCfg struct {
+	Addr string
+	// DownloadSize is number of bytes sent by download endpoint, unless request specifies size query parameter
+	DownloadSize int64
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// Handler serves download, upload and latency endpoints used by SpeedTester
+func Handler(cfg ServerCfg) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(DownloadPath, func(w http.ResponseWriter, r *http.Request) {
+		size := cfg.DownloadSize
+		if s := r.URL.Query().Get("size"); s != "" {
+			parsed, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || parsed < 0 {
+				http.Error(w, "invalid size", http.StatusBadRequest)
+				return
+			}
+			size = parsed
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		_, _ = io.Copy(w, io.LimitReader(zeros{}, size))
+	})
+	mux.HandleFunc(UploadPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc(PingPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// Serve runs bandwidth test server until ctx gets cancelled
+func Serve(ctx context.Context, cfg ServerCfg) error {
+	logger := logging.For(cfg.Logger, "httpspeed")
+	server := http.Server{Addr: cfg.Addr, Handler: Handler(cfg)}
+	go func() {
+		<-ctx.Done()
+		err := server.Close()
+		if err != nil {
+			logger.Error("could not stop speed test server", "err", err)
+		}
+	}()
+
+	logger.Info("serving speed test", "address", cfg.Addr)
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// ForServer returns cfg with URLs pointing to the server started with Serve at baseUrl.
+// Every download stream fetches downloadSize bytes
+func ForServer(baseUrl string, downloadSize int64, cfg Cfg) Cfg {
+	cfg.DownloadUrl = baseUrl + DownloadPath + "?size=" + strconv.FormatInt(downloadSize, 10)
+	cfg.UploadUrl = baseUrl + UploadPath
+	cfg.PingUrl = baseUrl + PingPath
+	return cfg
+}
@@ -0,0 +1,74 @@
+package httpspeed_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/httpspeed"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(httpspeed.Handler(httpspeed.ServerCfg{DownloadSize: 1024}))
+	t.Cleanup(server.Close)
+
+	t.Run("should download default size", func(t *testing.T) {
+		assertDownloaded(t, server.URL+httpspeed.DownloadPath, 1024)
+	})
+
+	t.Run("should download requested size", func(t *testing.T) {
+		assertDownloaded(t, server.URL+httpspeed.DownloadPath+"?size=10", 10)
+	})
+
+	t.Run("should reject invalid size", func(t *testing.T) {
+		resp, err := http.Get(server.URL + httpspeed.DownloadPath + "?size=-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, actual: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should reject upload with GET", func(t *testing.T) {
+		resp, err := http.Get(server.URL + httpspeed.UploadPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Fatalf("expected status 405, actual: %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestSpeedTester_TestAgainstServer(t *testing.T) {
+	server := httptest.NewServer(httpspeed.Handler(httpspeed.ServerCfg{}))
+	t.Cleanup(server.Close)
+	cfg := httpspeed.ForServer(server.URL, payloadSize, httpspeed.Cfg{UploadSize: payloadSize, Streams: 2})
+
+	speed, err := httpspeed.NewSpeedTester(cfg).Test(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speed.Download <= 0 || speed.Upload <= 0 || speed.Ping <= 0 {
+		t.Fatalf("expected positive measurements, actual: %v", speed)
+	}
+}
+
+func assertDownloaded(t *testing.T, url string, size int) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != size {
+		t.Fatalf("expected %d bytes, actual: %d", size, len(body))
+	}
+}
@@ -0,0 +1,206 @@
+package httpspeed
+
+import (
+	"context"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+func NewSpeedTester(cfg Cfg) *SpeedTester {
+	if cfg.Streams < 1 {
+		cfg.Streams = 1
+	}
+	if cfg.PingCount < 1 {
+		cfg.PingCount = 1
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &SpeedTester{cfg: cfg, client: client, uploadClient: uploadClient(client), logger: logging.For(cfg.Logger, "httpspeed")}
+}
+
+type Cfg struct {
+	DownloadUrl, UploadUrl, PingUrl string
+	// UploadSize is number of bytes sent by every upload stream
+	UploadSize int64
+	// Streams is number of parallel downloads and uploads
+	Streams int
+	// WarmUp is excluded from throughput calculation, so TCP slow start does not lower the result
+	WarmUp time.Duration
+	// PingCount is number of requests sent to PingUrl. The fastest one is used as latency
+	PingCount int
+	Timeouts  Timeouts
+	Client    *http.Client
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// Timeouts limits duration of every phase of the speed test. Zero value means no limit
+type Timeouts struct {
+	Ping, Download, Upload time.Duration
+}
+
+// SpeedTester measures throughput by downloading and uploading payloads from user-specified URLs
+type SpeedTester struct {
+	cfg    Cfg
+	client *http.Client
+	// uploadClient counts bytes written to the connection, nil when transport of the client is not *http.Transport
+	uploadClient *http.Client
+	logger       *logging.Logger
+}
+
+func (t *SpeedTester) Test(ctx context.Context) (core.Speed, error) {
+	measurementTime := time.Now()
+	ping, err := t.ping(ctx)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(core.PingPhase, err)
+	}
+
+	download, err := t.measure(ctx, t.cfg.Timeouts.Download, t.download)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(core.DownloadPhase, err)
+	}
+
+	upload, err := t.measure(ctx, t.cfg.Timeouts.Upload, t.upload)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(core.UploadPhase, err)
+	}
+
+	return core.Speed{
+		Download:  download,
+		Upload:    upload,
+		Ping:      ping,
+		Timestamp: measurementTime,
+	}, nil
+}
+
+func (t *SpeedTester) ping(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := withTimeout(ctx, t.cfg.Timeouts.Ping)
+	defer cancel()
+
+	latency := time.Duration(-1)
+	for i := 0; i < t.cfg.PingCount; i++ {
+		start := time.Now()
+		err := t.do(ctx, t.client, http.MethodGet, t.cfg.PingUrl, nil, ioutil.Discard)
+		if err != nil {
+			return -1, err
+		}
+		rtt := time.Since(start)
+		if latency < 0 || rtt < latency {
+			latency = rtt
+		}
+	}
+	return latency, nil
+}
+
+func (t *SpeedTester) download(ctx context.Context, c *counter) error {
+	return t.do(ctx, t.client, http.MethodGet, t.cfg.DownloadUrl, nil, c)
+}
+
+// upload counts bytes written to the connection. Request body is read ahead of the connection, so counting it
+// would include bytes buffered by the transport, which have not been sent yet
+func (t *SpeedTester) upload(ctx context.Context, c *counter) error {
+	body := io.LimitReader(zeros{}, t.cfg.UploadSize)
+	if t.uploadClient == nil {
+		return t.do(ctx, t.client, http.MethodPost, t.cfg.UploadUrl, &countingReader{r: body, c: c}, ioutil.Discard)
+	}
+	return t.do(withCounter(ctx, c), t.uploadClient, http.MethodPost, t.cfg.UploadUrl, body, ioutil.Discard)
+}
+
+// measure runs stream in parallel and returns throughput in Mbps. Bytes transferred during warm-up are excluded
+func (t *SpeedTester) measure(ctx context.Context, max time.Duration, stream func(context.Context, *counter) error) (float64, error) {
+	ctx, cancel := withTimeout(ctx, max)
+	defer cancel()
+
+	c := &counter{}
+	start := time.Now()
+	warmedUp := make(chan sample, 1)
+	// without warm-up the sample could be taken after the transfer, so the whole transfer is measured instead
+	var warmUp *time.Timer
+	if t.cfg.WarmUp > 0 {
+		warmUp = time.AfterFunc(t.cfg.WarmUp, func() {
+			warmedUp <- sample{bytes: c.load(), at: time.Now()}
+		})
+	}
+
+	errC := make(chan error, t.cfg.Streams)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < t.cfg.Streams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := stream(ctx, c)
+			errC <- err
+			if err != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errC)
+	end := sample{bytes: c.load(), at: time.Now()}
+
+	for err := range errC {
+		if err != nil {
+			if warmUp != nil {
+				warmUp.Stop()
+			}
+			return -1, err
+		}
+	}
+
+	from := sample{bytes: 0, at: start}
+	if warmUp != nil {
+		if !warmUp.Stop() {
+			from = <-warmedUp
+		} else {
+			t.logger.Warn("transfer finished before warm-up, measuring whole transfer", "warm_up", t.cfg.WarmUp)
+		}
+	}
+	return megabits(end.bytes-from.bytes) / end.at.Sub(from.at).Seconds(), nil
+}
+
+func (t *SpeedTester) do(ctx context.Context, client *http.Client, method, url string, body io.Reader, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if method == http.MethodPost {
+		req.ContentLength = t.cfg.UploadSize
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d from: %s", resp.StatusCode, url)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func withTimeout(ctx context.Context, max time.Duration) (context.Context, context.CancelFunc) {
+	if max > 0 {
+		return context.WithTimeout(ctx, max)
+	}
+	return context.WithCancel(ctx)
+}
+
+func megabits(bytes int64) float64 {
+	return float64(bytes) * 8 / 1000 / 1000
+}
+
+type sample struct {
+	bytes int64
+	at    time.Time
+}
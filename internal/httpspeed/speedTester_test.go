@@ -0,0 +1,177 @@
+package httpspeed_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/httpspeed"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const payloadSize = 1 << 20
+
+func TestSpeedTester_Test(t *testing.T) {
+	t.Run("should measure download, upload and ping", func(t *testing.T) {
+		server := newServer(t, &handler{})
+		tester := httpspeed.NewSpeedTester(cfg(server.URL))
+
+		speed, err := tester.Test(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speed.Download <= 0 || speed.Upload <= 0 {
+			t.Fatalf("expected positive download and upload, actual: %v", speed)
+		}
+		if speed.Ping <= 0 {
+			t.Fatalf("expected positive ping, actual: %v", speed.Ping)
+		}
+	})
+
+	t.Run("should upload configured payload with every stream", func(t *testing.T) {
+		h := &handler{}
+		server := newServer(t, h)
+		c := cfg(server.URL)
+		c.Streams = 3
+		tester := httpspeed.NewSpeedTester(c)
+
+		_, err := tester.Test(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uploaded := atomic.LoadInt64(&h.uploaded); uploaded != 3*payloadSize {
+			t.Fatalf("expected %d bytes uploaded, actual: %d", 3*payloadSize, uploaded)
+		}
+	})
+
+	t.Run("should measure upload with custom transport of the client", func(t *testing.T) {
+		server := newServer(t, &handler{})
+		c := cfg(server.URL)
+		c.Client = &http.Client{Transport: roundTripper(http.DefaultTransport.RoundTrip)}
+		tester := httpspeed.NewSpeedTester(c)
+
+		speed, err := tester.Test(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speed.Upload <= 0 {
+			t.Fatalf("expected positive upload, actual: %v", speed.Upload)
+		}
+	})
+
+	t.Run("should exclude warm-up from throughput", func(t *testing.T) {
+		server := newServer(t, &handler{delay: 50 * time.Millisecond})
+		c := cfg(server.URL)
+		c.WarmUp = 40 * time.Millisecond
+		tester := httpspeed.NewSpeedTester(c)
+
+		speed, err := tester.Test(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		whole := float64(payloadSize) * 8 / 1000 / 1000 / (50 * time.Millisecond).Seconds()
+		if speed.Download <= whole {
+			t.Fatalf("expected download: %v to be faster than including warm-up: %v", speed.Download, whole)
+		}
+	})
+
+	t.Run("should return error of failed phase", func(t *testing.T) {
+		tests := map[string]struct {
+			handler *handler
+			cfg     func(httpspeed.Cfg) httpspeed.Cfg
+			phase   core.Phase
+			kind    core.Kind
+		}{
+			"when server fails to respond to download": {
+				handler: &handler{failDownload: true},
+				phase:   core.DownloadPhase,
+				kind:    core.UnknownKind,
+			},
+			"when upload times out": {
+				handler: &handler{delay: 100 * time.Millisecond},
+				cfg: func(c httpspeed.Cfg) httpspeed.Cfg {
+					c.Timeouts.Upload = time.Millisecond
+					return c
+				},
+				phase: core.UploadPhase,
+				kind:  core.TimeoutKind,
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				server := newServer(t, tt.handler)
+				c := cfg(server.URL)
+				if tt.cfg != nil {
+					c = tt.cfg(c)
+				}
+
+				_, err := httpspeed.NewSpeedTester(c).Test(context.Background())
+				e := core.AsError(err)
+				if e == nil {
+					t.Fatalf("expected *core.Error, actual: %v", err)
+				}
+				if e.Phase != tt.phase || e.Kind != tt.kind {
+					t.Fatalf("expected %s error of phase: %s, actual: %v", tt.kind, tt.phase, e)
+				}
+			})
+		}
+	})
+}
+
+func cfg(url string) httpspeed.Cfg {
+	return httpspeed.Cfg{
+		DownloadUrl: url + "/download",
+		UploadUrl:   url + "/upload",
+		PingUrl:     url + "/ping",
+		UploadSize:  payloadSize,
+		Streams:     2,
+		PingCount:   3,
+	}
+}
+
+func newServer(t *testing.T, h *handler) *httptest.Server {
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type handler struct {
+	delay        time.Duration
+	failDownload bool
+	uploaded     int64
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ping":
+		w.WriteHeader(http.StatusNoContent)
+	case "/download":
+		if h.failDownload {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// first byte is delayed to simulate slow start
+		_, _ = w.Write([]byte{0})
+		w.(http.Flusher).Flush()
+		time.Sleep(h.delay)
+		_, _ = io.Copy(w, strings.NewReader(strings.Repeat("0", payloadSize-1)))
+	case "/upload":
+		time.Sleep(h.delay)
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		atomic.AddInt64(&h.uploaded, n)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
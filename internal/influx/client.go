@@ -2,65 +2,192 @@ package influx
 
 import (
 	"context"
+	"fmt"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
-	"log"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
 type Client interface {
 	core.Storage
+	core.OutageStorage
 	Ping(ctx context.Context) error
 }
 
 func NewClient(cfg Cfg) (*AsyncClient, error) {
 	c := influxdb2.NewClient(cfg.Url, cfg.Token)
 	w := c.WriteAPI(cfg.Organization, cfg.Bucket)
-	return &AsyncClient{writer: w, client: c, points: cfg.Points}, nil
+	return &AsyncClient{
+		writer:       w,
+		client:       c,
+		points:       cfg.Points,
+		organization: cfg.Organization,
+		bucket:       cfg.Bucket,
+		logger:       logging.For(cfg.Logger, "influx"),
+	}, nil
 }
 
 type Cfg struct {
 	Url, Token, Organization, Bucket string
 	Points                           PointsCfg
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
 }
 
 type PointsCfg struct {
-	Measurement string
-	Tags        map[string]string
+	Measurement, OutageMeasurement string
+	Tags                           map[string]string
 }
 
 type AsyncClient struct {
-	client influxdb2.Client
-	writer api.WriteAPI
-	points PointsCfg
+	client               influxdb2.Client
+	writer               api.WriteAPI
+	points               PointsCfg
+	organization, bucket string
+	logger               *logging.Logger
 }
 
-func (c *AsyncClient) Ping(ctx context.Context) error {
-	_, err := c.client.Ping(ctx)
-	return err
+const instrumentation = "github.com/paluszkiewiczB/speedtest/internal/influx"
+
+// start starts span of the operation on the bucket
+func (c *AsyncClient) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("db.system", "influxdb"), attribute.String("influx.bucket", c.bucket))
+	return otel.Tracer(instrumentation).Start(ctx, "influx."+operation,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func (c *AsyncClient) Ping(ctx context.Context) (err error) {
+	ctx, span := c.start(ctx, "ping")
+	defer func() { tracing.End(span, err) }()
+	_, err = c.client.Ping(ctx)
+	return core.Classify(core.StoragePhase, err)
+}
+
+func (c *AsyncClient) Push(ctx context.Context, speed core.Speed) (err error) {
+	ctx, span := c.start(ctx, "push", attribute.String("influx.measurement", c.points.Measurement))
+	defer func() { tracing.End(span, err) }()
+	fields := map[string]interface{}{
+		"download": speed.Download,
+		"upload":   speed.Upload,
+		"ping":     speed.Ping.Milliseconds(),
+	}
+	return c.write(ctx, influxdb2.NewPoint(c.points.Measurement, c.tags(speed.Tags), fields, speed.Timestamp))
+}
+
+// tags merges configured tags with tags of the measurement. Tags of the measurement take precedence
+func (c *AsyncClient) tags(measurement map[string]string) map[string]string {
+	if len(measurement) == 0 {
+		return c.points.Tags
+	}
+	tags := make(map[string]string, len(c.points.Tags)+len(measurement))
+	for k, v := range c.points.Tags {
+		tags[k] = v
+	}
+	for k, v := range measurement {
+		tags[k] = v
+	}
+	return tags
+}
+
+// PushOutage writes outage as a point at its start. Point written when the outage ends overwrites the one written
+// when it started
+func (c *AsyncClient) PushOutage(ctx context.Context, outage core.Outage) (err error) {
+	ctx, span := c.start(ctx, "push_outage", attribute.String("influx.measurement", c.points.OutageMeasurement))
+	defer func() { tracing.End(span, err) }()
+	duration := 0.0
+	if !outage.Ongoing() {
+		duration = outage.End.Sub(outage.Start).Seconds()
+	}
+	fields := map[string]interface{}{
+		"ongoing":  outage.Ongoing(),
+		"duration": duration,
+	}
+	return c.write(ctx, influxdb2.NewPoint(c.points.OutageMeasurement, c.points.Tags, fields, outage.Start))
 }
 
-func (c *AsyncClient) Push(ctx context.Context, speed core.Speed) error {
+func (c *AsyncClient) write(ctx context.Context, p *write.Point) error {
 	eC := make(chan error, 1)
 	go func() {
-		fields := map[string]interface{}{
-			"download": speed.Download,
-			"upload":   speed.Upload,
-			"ping":     speed.Ping.Milliseconds(),
-		}
-		p := influxdb2.NewPoint(c.points.Measurement, c.points.Tags, fields, speed.Timestamp)
-		log.Printf("Writing point at: %v", speed.Timestamp)
+		c.logger.Debug("writing point", "measurement", p.Name(), "time", p.Time())
 		c.writer.WritePoint(p)
 		eC <- nil
 	}()
 
 	select {
 	case <-ctx.Done():
-		return context.Canceled
+		return core.Classify(core.StoragePhase, context.Canceled)
 	case err := <-eC:
-		return err
+		return core.Classify(core.StoragePhase, err)
+	}
+}
+
+// Query reads speeds written to the measurement. Points written with WriteAPI are visible after they are flushed
+func (c *AsyncClient) Query(ctx context.Context, from, to time.Time) (speeds []core.Speed, err error) {
+	ctx, span := c.start(ctx, "query", attribute.String("influx.measurement", c.points.Measurement))
+	defer func() { tracing.End(span, err) }()
+	flux := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: %s, stop: %s)
+  |> filter(fn: (r) => r._measurement == %q)
+  |> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+  |> group()
+  |> sort(columns: ["_time"])`, c.bucket, from.UTC().Format(time.RFC3339Nano), to.UTC().Format(time.RFC3339Nano), c.points.Measurement)
+
+	result, err := c.client.QueryAPI(c.organization).Query(ctx, flux)
+	if err != nil {
+		return nil, core.Classify(core.StoragePhase, err)
+	}
+	defer result.Close()
+
+	speeds = make([]core.Speed, 0)
+	for result.Next() {
+		speeds = append(speeds, toSpeed(result.Record()))
 	}
+	return speeds, core.Classify(core.StoragePhase, result.Err())
+}
 
+// toSpeed converts pivoted record. Columns which are neither fields nor internal columns are tags
+func toSpeed(r *query.FluxRecord) core.Speed {
+	s := core.Speed{Timestamp: r.Time()}
+	tags := make(map[string]string)
+	for k, v := range r.Values() {
+		switch k {
+		case "download":
+			s.Download = toFloat(v)
+		case "upload":
+			s.Upload = toFloat(v)
+		case "ping":
+			s.Ping = time.Duration(toFloat(v) * float64(time.Millisecond))
+		case "result", "table", "_start", "_stop", "_time", "_measurement":
+		default:
+			if tag, ok := v.(string); ok {
+				tags[k] = tag
+			}
+		}
+	}
+	if len(tags) > 0 {
+		s.Tags = tags
+	}
+	return s
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return 0
 }
 
 func (c *AsyncClient) Close() error {
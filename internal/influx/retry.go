@@ -2,6 +2,7 @@ package influx
 
 import (
 	"context"
+	"fmt"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/resilience"
 	"time"
@@ -38,12 +39,36 @@ func (c *RetryingClient) Push(ctx context.Context, speed core.Speed) error {
 	})
 }
 
+func (c *RetryingClient) PushOutage(ctx context.Context, outage core.Outage) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.delegate.PushOutage(ctx, outage)
+	})
+}
+
 func (c *RetryingClient) Close() error {
 	return c.retry(context.Background(), func(ctx context.Context) error {
 		return c.delegate.Close()
 	})
 }
 
+// Query reads speeds from the delegate, if it is core.Queryable
+func (c *RetryingClient) Query(ctx context.Context, from, to time.Time) ([]core.Speed, error) {
+	q, ok := c.delegate.(core.Queryable)
+	if !ok {
+		return nil, fmt.Errorf("storage is not queryable: %T", c.delegate)
+	}
+	var speeds []core.Speed
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		speeds, err = q.Query(ctx, from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return speeds, nil
+}
+
 func (c *RetryingClient) Ping(ctx context.Context) error {
 	return c.retry(ctx, func(ctx context.Context) error {
 		return c.delegate.Ping(ctx)
@@ -6,6 +6,7 @@ import (
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/influx"
 	"testing"
+	"time"
 )
 
 func TestRetryingClient_TestConnection(t *testing.T) {
@@ -128,6 +129,47 @@ func TestRetryingClient_Push(t *testing.T) {
 	}
 }
 
+func TestRetryingClient_PushOutage(t *testing.T) {
+	client := &failingClient{timeToFail: 2}
+	retrying := influx.Retrying(client, influx.RetryCfg{Times: 3, Wait: 0})
+	err := retrying.PushOutage(context.Background(), core.Outage{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.failedTimes != 2 {
+		t.Fatalf("expected client to fail 2 times, actual count: %d", client.failedTimes)
+	}
+
+	if client.push != 3 {
+		t.Fatalf("expected client to push: 3 times, actual count: %d", client.push)
+	}
+}
+
+func TestRetryingClient_Query(t *testing.T) {
+	client := &queryableClient{failingClient: failingClient{timeToFail: 2}}
+	retrying := influx.Retrying(client, influx.RetryCfg{Times: 3, Wait: 0})
+	speeds, err := retrying.Query(context.Background(), time.Unix(0, 0), time.Unix(1, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(speeds) != 1 {
+		t.Fatalf("expected 1 speed, actual: %v", speeds)
+	}
+	if client.failedTimes != 2 {
+		t.Fatalf("expected client to fail 2 times, actual count: %d", client.failedTimes)
+	}
+}
+
+func TestRetryingClient_QueryNotQueryable(t *testing.T) {
+	retrying := influx.Retrying(&failingClient{}, influx.RetryCfg{Times: 3, Wait: 0})
+	_, err := retrying.Query(context.Background(), time.Unix(0, 0), time.Unix(1, 0))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 func TestRetryingClient_Close(t *testing.T) {
 	tests := map[string]struct {
 		client       *failingClient
@@ -197,6 +239,11 @@ func (f *failingClient) Push(_ context.Context, _ core.Speed) error {
 	return f.tryToFail()
 }
 
+func (f *failingClient) PushOutage(_ context.Context, _ core.Outage) error {
+	f.push++
+	return f.tryToFail()
+}
+
 func (f *failingClient) Close() error {
 	f.close++
 	return f.tryToFail()
@@ -223,3 +270,14 @@ func (f *failingClient) fail() error {
 	f.failedTimes++
 	return errors.New("error")
 }
+
+type queryableClient struct {
+	failingClient
+}
+
+func (q *queryableClient) Query(_ context.Context, from, _ time.Time) ([]core.Speed, error) {
+	if err := q.tryToFail(); err != nil {
+		return nil, err
+	}
+	return []core.Speed{{Timestamp: from}}, nil
+}
@@ -2,6 +2,7 @@ package influx
 
 import (
 	"context"
+	"fmt"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/resilience"
 	"time"
@@ -18,12 +19,36 @@ func (c *TimeOutingClient) Push(ctx context.Context, speed core.Speed) error {
 	})
 }
 
+func (c *TimeOutingClient) PushOutage(ctx context.Context, outage core.Outage) error {
+	return c.TimeOut(ctx, func(ctx context.Context) error {
+		return c.Delegate.PushOutage(ctx, outage)
+	})
+}
+
 func (c *TimeOutingClient) Close() error {
 	return c.TimeOut(context.Background(), func(ctx context.Context) error {
 		return c.Delegate.Close()
 	})
 }
 
+// Query reads speeds from the delegate, if it is core.Queryable
+func (c *TimeOutingClient) Query(ctx context.Context, from, to time.Time) ([]core.Speed, error) {
+	q, ok := c.Delegate.(core.Queryable)
+	if !ok {
+		return nil, fmt.Errorf("storage is not queryable: %T", c.Delegate)
+	}
+	speedsC := make(chan []core.Speed, 1)
+	err := c.TimeOut(ctx, func(ctx context.Context) error {
+		speeds, err := q.Query(ctx, from, to)
+		speedsC <- speeds
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return <-speedsC, nil
+}
+
 func (c *TimeOutingClient) Ping(ctx context.Context) error {
 	return c.TimeOut(ctx, func(ctx context.Context) error {
 		return c.Delegate.Ping(ctx)
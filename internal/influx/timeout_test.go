@@ -116,6 +116,10 @@ func (c *sleepingClient) Push(_ context.Context, _ core.Speed) error {
 	return c.sleep()
 }
 
+func (c *sleepingClient) PushOutage(_ context.Context, _ core.Outage) error {
+	return c.sleep()
+}
+
 func (c *sleepingClient) Close() error {
 	return c.sleep()
 }
@@ -0,0 +1,154 @@
+package iperf3
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// state is a single signed byte sent over control connection to drive the test
+type state int8
+
+const (
+	testStart       state = 1
+	testRunning     state = 2
+	testEnd         state = 4
+	paramExchange   state = 9
+	createStreams   state = 10
+	serverTerminate state = 11
+	clientTerminate state = 12
+	exchangeResults state = 13
+	displayResults  state = 14
+	iperfDone       state = 16
+	accessDenied    state = -1
+	serverError     state = -2
+)
+
+const cookieSize = 37
+
+// params are sent by the client in paramExchange state. Server switches to reverse mode when reverse key is present
+type params struct {
+	TCP           bool   `json:"tcp"`
+	Omit          int    `json:"omit"`
+	Time          int    `json:"time"`
+	Parallel      int    `json:"parallel"`
+	Reverse       bool   `json:"reverse,omitempty"`
+	Len           int    `json:"len"`
+	PacingTimer   int    `json:"pacing_timer"`
+	ClientVersion string `json:"client_version"`
+}
+
+// results are exchanged by both sides in exchangeResults state
+type results struct {
+	CPUUtilTotal         float64        `json:"cpu_util_total"`
+	CPUUtilUser          float64        `json:"cpu_util_user"`
+	CPUUtilSystem        float64        `json:"cpu_util_system"`
+	SenderHasRetransmits int            `json:"sender_has_retransmits"`
+	Streams              []streamResult `json:"streams"`
+}
+
+type streamResult struct {
+	ID          int     `json:"id"`
+	Bytes       int64   `json:"bytes"`
+	Retransmits int     `json:"retransmits"`
+	Jitter      float64 `json:"jitter"`
+	Errors      int     `json:"errors"`
+	Packets     int     `json:"packets"`
+	StartTime   float64 `json:"start_time"`
+	EndTime     float64 `json:"end_time"`
+}
+
+// throughput returns Mbps of all the streams
+func (r results) throughput() float64 {
+	bytes, seconds := int64(0), 0.0
+	for _, s := range r.Streams {
+		bytes += s.Bytes
+		if d := s.EndTime - s.StartTime; d > seconds {
+			seconds = d
+		}
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / 1000 / 1000 / seconds
+}
+
+// streamID returns id assigned by iperf3 to i-th stream. iperf3 skips id 2
+func streamID(i int) int {
+	if i == 0 {
+		return 1
+	}
+	return i + 2
+}
+
+// newCookie returns random, null-terminated cookie identifying the test
+func newCookie() ([]byte, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	cookie := make([]byte, cookieSize)
+	if _, err := rand.Read(cookie); err != nil {
+		return nil, err
+	}
+	for i := range cookie {
+		cookie[i] = alphabet[int(cookie[i])%len(alphabet)]
+	}
+	cookie[cookieSize-1] = 0
+	return cookie, nil
+}
+
+func readState(r io.Reader) (state, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+	return state(int8(b[0])), nil
+}
+
+func writeState(w io.Writer, s state) error {
+	_, err := w.Write([]byte{byte(s)})
+	return err
+}
+
+// writeJSON writes v prefixed with its length as 32-bit big-endian integer
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(b)))
+	if _, err = w.Write(append(size, b...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// maxJSONSize limits JSON read from the server, which could otherwise make the client allocate up to 4 GB
+const maxJSONSize = 1 << 20
+
+// readJSON reads v prefixed with its length as 32-bit big-endian integer, up to maxJSONSize bytes
+func readJSON(r io.Reader, v interface{}) error {
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(r, size); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size)
+	if n > maxJSONSize {
+		return fmt.Errorf("iperf3 JSON too large: %d bytes, max: %d", n, maxJSONSize)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// readServerError reads error codes sent by server after serverError state
+func readServerError(r io.Reader) error {
+	codes := make([]int32, 2)
+	if err := binary.Read(r, binary.BigEndian, codes); err != nil {
+		return fmt.Errorf("server error, could not read error code: %w", err)
+	}
+	return fmt.Errorf("server error: %d, errno: %d", codes[0], codes[1])
+}
@@ -0,0 +1,320 @@
+package iperf3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	clientVersion    = "3.1.3"
+	defaultBlockSize = 128 * 1024
+)
+
+func NewSpeedTester(cfg Cfg) *SpeedTester {
+	if cfg.Streams < 1 {
+		cfg.Streams = 1
+	}
+	if cfg.BlockSize < 1 {
+		cfg.BlockSize = defaultBlockSize
+	}
+	if cfg.Duration < time.Second {
+		cfg.Duration = time.Second
+	}
+	dialer := cfg.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	return &SpeedTester{cfg: cfg, dialer: dialer, logger: logging.For(cfg.Logger, "iperf3")}
+}
+
+type Cfg struct {
+	// Address of iperf3 server in host:port format
+	Address string
+	Streams int
+	// Duration of both download and upload test. iperf3 supports only whole seconds
+	Duration  time.Duration
+	BlockSize int
+	Timeouts  Timeouts
+	Dialer    *net.Dialer
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// Timeouts limits duration of every phase of the speed test. Ping limits handshake of every test. Zero value means
+// no limit
+type Timeouts struct {
+	Ping, Download, Upload time.Duration
+}
+
+// SpeedTester speaks iperf3 protocol over TCP. Upload is measured in normal mode, download in reverse mode.
+// Ping is the shorter round trip of handshakes of both tests
+type SpeedTester struct {
+	cfg    Cfg
+	dialer *net.Dialer
+	logger *logging.Logger
+}
+
+func (t *SpeedTester) Test(ctx context.Context) (core.Speed, error) {
+	measurementTime := time.Now()
+	download, downloadPing, err := t.run(ctx, t.cfg.Timeouts.Download, true)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(core.DownloadPhase, err)
+	}
+
+	upload, uploadPing, err := t.run(ctx, t.cfg.Timeouts.Upload, false)
+	if err != nil {
+		return core.InvalidSpeed, core.Classify(core.UploadPhase, err)
+	}
+
+	ping := downloadPing
+	if uploadPing < ping {
+		ping = uploadPing
+	}
+	return core.Speed{
+		Download:  download,
+		Upload:    upload,
+		Ping:      ping,
+		Timestamp: measurementTime,
+	}, nil
+}
+
+// run executes single iperf3 test and returns throughput in Mbps measured by the receiving side and round trip of
+// the handshake. Failures of connecting and the handshake are classified as ping phase
+func (t *SpeedTester) run(ctx context.Context, max time.Duration, reverse bool) (float64, time.Duration, error) {
+	ctx, cancel := withTimeout(ctx, max)
+	defer cancel()
+
+	cookie, err := newCookie()
+	if err != nil {
+		return -1, -1, err
+	}
+	dialCtx, cancelDial := withTimeout(ctx, t.cfg.Timeouts.Ping)
+	control, err := t.dialer.DialContext(dialCtx, "tcp", t.cfg.Address)
+	cancelDial()
+	if err != nil {
+		return -1, -1, core.Classify(core.PingPhase, t.fail(ctx, err))
+	}
+	defer control.Close()
+	go func() {
+		<-ctx.Done()
+		_ = control.Close()
+	}()
+
+	s, ping, err := t.handshake(control, cookie)
+	if err != nil {
+		return -1, -1, core.Classify(core.PingPhase, t.fail(ctx, err))
+	}
+
+	test := &test{reverse: reverse, blockSize: t.cfg.BlockSize, logger: t.logger}
+	defer test.close()
+	var serverResults results
+	for {
+		switch s {
+		case paramExchange:
+			err = writeJSON(control, t.params(reverse))
+		case createStreams:
+			err = test.connect(ctx, t.dialer, t.cfg.Address, cookie, t.cfg.Streams)
+		case testStart:
+		case testRunning:
+			test.start()
+			select {
+			case <-time.After(t.seconds()):
+			case <-ctx.Done():
+				return -1, -1, ctx.Err()
+			}
+			test.stop()
+			err = writeState(control, testEnd)
+		case exchangeResults:
+			err = writeJSON(control, test.results())
+			if err == nil {
+				err = readJSON(control, &serverResults)
+			}
+		case displayResults:
+			err = writeState(control, iperfDone)
+			if err != nil {
+				return -1, -1, t.fail(ctx, err)
+			}
+			if reverse {
+				return test.results().throughput(), ping, nil
+			}
+			return serverResults.throughput(), ping, nil
+		case accessDenied:
+			return -1, -1, errors.New("iperf3 server is busy running a test")
+		case serverError:
+			return -1, -1, readServerError(control)
+		case serverTerminate, clientTerminate:
+			return -1, -1, fmt.Errorf("iperf3 test terminated with state: %d", s)
+		default:
+			return -1, -1, fmt.Errorf("unexpected iperf3 state: %d", s)
+		}
+		if err != nil {
+			return -1, -1, t.fail(ctx, err)
+		}
+		if s, err = readState(control); err != nil {
+			return -1, -1, t.fail(ctx, err)
+		}
+	}
+}
+
+// handshake sends the cookie and reads the first state of the server. Ping is the time between sending the cookie
+// and receiving the state. Handshake is limited by the ping timeout
+func (t *SpeedTester) handshake(control net.Conn, cookie []byte) (state, time.Duration, error) {
+	if t.cfg.Timeouts.Ping > 0 {
+		if err := control.SetDeadline(time.Now().Add(t.cfg.Timeouts.Ping)); err != nil {
+			return 0, -1, err
+		}
+	}
+	start := time.Now()
+	if _, err := control.Write(cookie); err != nil {
+		return 0, -1, err
+	}
+	s, err := readState(control)
+	if err != nil {
+		return 0, -1, err
+	}
+	ping := time.Since(start)
+	return s, ping, control.SetDeadline(time.Time{})
+}
+
+// fail prefers context error over the error of closed connection
+func (t *SpeedTester) fail(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (t *SpeedTester) params(reverse bool) params {
+	return params{
+		TCP:           true,
+		Time:          int(t.seconds() / time.Second),
+		Parallel:      t.cfg.Streams,
+		Reverse:       reverse,
+		Len:           t.cfg.BlockSize,
+		PacingTimer:   1000,
+		ClientVersion: clientVersion,
+	}
+}
+
+func (t *SpeedTester) seconds() time.Duration {
+	return time.Duration(math.Round(t.cfg.Duration.Seconds())) * time.Second
+}
+
+// test transfers data over data streams. Bytes are counted only between start and stop
+type test struct {
+	reverse   bool
+	blockSize int
+	streams   []net.Conn
+	bytes     []int64
+	measuring int32
+	started   time.Time
+	took      time.Duration
+	wg        sync.WaitGroup
+	logger    *logging.Logger
+}
+
+func (t *test) connect(ctx context.Context, dialer *net.Dialer, address string, cookie []byte, count int) error {
+	for i := 0; i < count; i++ {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		t.streams = append(t.streams, conn)
+		if _, err = conn.Write(cookie); err != nil {
+			return err
+		}
+	}
+	t.bytes = make([]int64, count)
+	return nil
+}
+
+func (t *test) start() {
+	t.started = time.Now()
+	atomic.StoreInt32(&t.measuring, 1)
+	for i, conn := range t.streams {
+		t.wg.Add(1)
+		go func(i int, conn net.Conn) {
+			defer t.wg.Done()
+			var err error
+			if t.reverse {
+				err = t.receive(conn, &t.bytes[i])
+			} else {
+				err = t.send(conn, &t.bytes[i])
+			}
+			if err != nil && atomic.LoadInt32(&t.measuring) == 1 {
+				t.logger.Warn("iperf3 stream failed", "stream", streamID(i), "err", err)
+			}
+		}(i, conn)
+	}
+}
+
+func (t *test) stop() {
+	atomic.StoreInt32(&t.measuring, 0)
+	t.took = time.Since(t.started)
+}
+
+// send writes blocks until the test is stopped
+func (t *test) send(conn net.Conn, counter *int64) error {
+	block := make([]byte, t.blockSize)
+	for atomic.LoadInt32(&t.measuring) == 1 {
+		n, err := conn.Write(block)
+		atomic.AddInt64(counter, int64(n))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive reads until the stream is closed. Bytes received after the test is stopped are discarded
+func (t *test) receive(conn net.Conn, counter *int64) error {
+	block := make([]byte, t.blockSize)
+	for {
+		n, err := conn.Read(block)
+		if atomic.LoadInt32(&t.measuring) == 1 {
+			atomic.AddInt64(counter, int64(n))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *test) results() results {
+	r := results{SenderHasRetransmits: 0, Streams: make([]streamResult, len(t.streams))}
+	for i := range t.streams {
+		r.Streams[i] = streamResult{
+			ID:          streamID(i),
+			Bytes:       atomic.LoadInt64(&t.bytes[i]),
+			Retransmits: -1,
+			EndTime:     t.took.Seconds(),
+		}
+	}
+	return r
+}
+
+func (t *test) close() {
+	for _, conn := range t.streams {
+		_ = conn.Close()
+	}
+	t.wg.Wait()
+}
+
+func withTimeout(ctx context.Context, max time.Duration) (context.Context, context.CancelFunc) {
+	if max > 0 {
+		return context.WithTimeout(ctx, max)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,306 @@
+package iperf3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/iperf3"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSpeedTester_Test(t *testing.T) {
+	t.Run("should measure download in reverse mode and upload in normal mode", func(t *testing.T) {
+		s := newStub(t, stubMode{})
+		tester := iperf3.NewSpeedTester(iperf3.Cfg{Address: s.address(), Streams: 2, Duration: time.Second})
+
+		speed, err := tester.Test(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speed.Download <= 0 || speed.Upload <= 0 || speed.Ping <= 0 {
+			t.Fatalf("expected positive measurements, actual: %v", speed)
+		}
+
+		if len(s.params) != 2 {
+			t.Fatalf("expected 2 tests, actual: %d", len(s.params))
+		}
+		if s.withoutCookie != 0 {
+			t.Errorf("expected cookie sent over every control connection, %d connections without it", s.withoutCookie)
+		}
+		if _, ok := s.params[0]["reverse"]; !ok {
+			t.Errorf("expected download test in reverse mode, params: %v", s.params[0])
+		}
+		if _, ok := s.params[1]["reverse"]; ok {
+			t.Errorf("expected upload test in normal mode, params: %v", s.params[1])
+		}
+		if s.params[1]["parallel"] != 2.0 || s.params[1]["time"] != 1.0 {
+			t.Errorf("expected 2 parallel streams for 1 second, params: %v", s.params[1])
+		}
+		ids := make([]int, 0)
+		for _, stream := range s.clientResults[1].Streams {
+			ids = append(ids, stream.ID)
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+			t.Errorf("expected stream ids assigned as by iperf3: [1 3], actual: %v", ids)
+		}
+	})
+
+	t.Run("should return error of failed phase", func(t *testing.T) {
+		tests := map[string]struct {
+			mode  stubMode
+			cfg   func(iperf3.Cfg) iperf3.Cfg
+			phase core.Phase
+			kind  core.Kind
+			msg   string
+		}{
+			"when server is busy": {
+				mode:  stubMode{busy: true},
+				phase: core.DownloadPhase,
+				msg:   "busy",
+			},
+			"when server fails": {
+				mode:  stubMode{serverError: true},
+				phase: core.DownloadPhase,
+				msg:   "server error: 111",
+			},
+			"when server does not respond to the cookie": {
+				mode: stubMode{silent: true},
+				cfg: func(c iperf3.Cfg) iperf3.Cfg {
+					c.Timeouts.Ping = 10 * time.Millisecond
+					return c
+				},
+				phase: core.PingPhase,
+				kind:  core.TimeoutKind,
+			},
+			"when server sends too large results": {
+				mode:  stubMode{hugeResults: true},
+				phase: core.DownloadPhase,
+				msg:   "too large",
+			},
+			"when test exceeds timeout": {
+				mode: stubMode{},
+				cfg: func(c iperf3.Cfg) iperf3.Cfg {
+					c.Timeouts.Download = 10 * time.Millisecond
+					return c
+				},
+				phase: core.DownloadPhase,
+				kind:  core.TimeoutKind,
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				s := newStub(t, tt.mode)
+				cfg := iperf3.Cfg{Address: s.address(), Duration: time.Second}
+				if tt.cfg != nil {
+					cfg = tt.cfg(cfg)
+				}
+
+				_, err := iperf3.NewSpeedTester(cfg).Test(context.Background())
+				e := core.AsError(err)
+				if e == nil {
+					t.Fatalf("expected *core.Error, actual: %v", err)
+				}
+				if e.Phase != tt.phase {
+					t.Fatalf("expected error of phase: %s, actual: %v", tt.phase, e)
+				}
+				if tt.kind != "" && e.Kind != tt.kind {
+					t.Fatalf("expected error of kind: %s, actual: %v", tt.kind, e)
+				}
+				if !strings.Contains(e.Error(), tt.msg) {
+					t.Fatalf("expected error containing: '%s', actual: %v", tt.msg, e)
+				}
+			})
+		}
+	})
+}
+
+type stubMode struct {
+	busy, serverError, silent, hugeResults bool
+}
+
+// stub is in-process iperf3 compatible server, handling one test at a time
+type stub struct {
+	l             net.Listener
+	mode          stubMode
+	params        []map[string]interface{}
+	clientResults []results
+	withoutCookie int
+}
+
+type results struct {
+	Streams []struct {
+		ID      int     `json:"id"`
+		Bytes   int64   `json:"bytes"`
+		EndTime float64 `json:"end_time"`
+	} `json:"streams"`
+}
+
+func newStub(t *testing.T, mode stubMode) *stub {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stub{l: l, mode: mode}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.handle(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+		<-done
+	})
+	return s
+}
+
+func (s *stub) address() string {
+	return s.l.Addr().String()
+}
+
+func (s *stub) handle(control net.Conn) {
+	defer control.Close()
+	cookie := make([]byte, 37)
+	if _, err := io.ReadFull(control, cookie); err != nil {
+		s.withoutCookie++
+		return
+	}
+
+	if s.mode.silent {
+		_, _ = io.Copy(ioutil.Discard, control)
+		return
+	}
+	if s.mode.busy {
+		_, _ = control.Write([]byte{0xff})
+		return
+	}
+
+	_, _ = control.Write([]byte{9})
+	params := make(map[string]interface{})
+	if err := readJSON(control, &params); err != nil {
+		return
+	}
+	s.params = append(s.params, params)
+
+	if s.mode.serverError {
+		_, _ = control.Write([]byte{0xfe})
+		_ = binary.Write(control, binary.BigEndian, []int32{111, 0})
+		return
+	}
+
+	_, _ = control.Write([]byte{10})
+	streams := make([]net.Conn, 0)
+	defer func() {
+		for _, stream := range streams {
+			_ = stream.Close()
+		}
+	}()
+	for i := 0; i < int(params["parallel"].(float64)); i++ {
+		stream, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		streams = append(streams, stream)
+		streamCookie := make([]byte, 37)
+		if _, err = io.ReadFull(stream, streamCookie); err != nil || !bytes.Equal(cookie, streamCookie) {
+			return
+		}
+	}
+
+	_, _ = control.Write([]byte{1, 2})
+	_, reverse := params["reverse"]
+	start := time.Now()
+	running := int32(1)
+	counters := make([]int64, len(streams))
+	wg := &sync.WaitGroup{}
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(stream net.Conn, counter *int64) {
+			defer wg.Done()
+			block := make([]byte, 1024)
+			for atomic.LoadInt32(&running) == 1 {
+				var n int
+				var err error
+				if reverse {
+					n, err = stream.Write(block)
+				} else {
+					n, err = stream.Read(block)
+				}
+				atomic.AddInt64(counter, int64(n))
+				if err != nil {
+					return
+				}
+			}
+		}(stream, &counters[i])
+	}
+
+	state := make([]byte, 1)
+	if _, err := io.ReadFull(control, state); err != nil || state[0] != 4 {
+		return
+	}
+	atomic.StoreInt32(&running, 0)
+	took := time.Since(start).Seconds()
+	serverResults := make([]map[string]interface{}, len(streams))
+	for i := range streams {
+		serverResults[i] = map[string]interface{}{"id": i + 1, "bytes": atomic.LoadInt64(&counters[i]), "start_time": 0, "end_time": took}
+	}
+
+	_, _ = control.Write([]byte{13})
+	var r results
+	if err := readJSON(control, &r); err != nil {
+		return
+	}
+	s.clientResults = append(s.clientResults, r)
+	if s.mode.hugeResults {
+		_ = binary.Write(control, binary.BigEndian, uint32(1<<31))
+		return
+	}
+	if err := writeJSON(control, map[string]interface{}{"streams": serverResults}); err != nil {
+		return
+	}
+	_, _ = control.Write([]byte{14})
+	_, _ = io.ReadFull(control, state)
+	for _, stream := range streams {
+		_ = stream.Close()
+	}
+	wg.Wait()
+}
+
+func readJSON(r io.Reader, v interface{}) error {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return err
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err = binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
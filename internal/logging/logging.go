@@ -0,0 +1,231 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of the log record. Zero value is Info
+type Level int8
+
+const (
+	Debug Level = iota - 1
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", l)
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return Debug, nil
+	case "", "info":
+		return Info, nil
+	case "warn", "warning":
+		return Warn, nil
+	case "error":
+		return Error, nil
+	}
+	return Info, fmt.Errorf("unsupported log level: %s", s)
+}
+
+// Format of the log record
+type Format string
+
+const (
+	Logfmt Format = "logfmt"
+	JSON   Format = "json"
+)
+
+type Cfg struct {
+	Level Level
+	// Format is Logfmt when empty
+	Format Format
+	// Packages override Level of loggers with the name, e.g. influx. Name matches also loggers named with its prefix,
+	// e.g. ookla matches ookla.phases
+	Packages map[string]Level
+	// Output is os.Stderr when nil
+	Output io.Writer
+}
+
+func New(cfg Cfg) (*Logger, error) {
+	switch cfg.Format {
+	case "":
+		cfg.Format = Logfmt
+	case Logfmt, JSON:
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", cfg.Format)
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stderr
+	}
+	s := &sink{w: cfg.Output, format: cfg.Format, level: cfg.Level, packages: cfg.Packages, now: time.Now}
+	return &Logger{sink: s, level: cfg.Level}, nil
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultLogger, _ = New(Cfg{})
+)
+
+// Default returns logger used by packages, which were not given one. It writes logfmt at Info level to os.Stderr,
+// unless it is replaced with SetDefault
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// For returns l, or Default when l is nil, named with name
+func For(l *Logger, name string) *Logger {
+	if l == nil {
+		l = Default()
+	}
+	return l.Named(name)
+}
+
+// Logger writes structured records. Arguments of Debug, Info, Warn and Error are pairs of keys and values,
+// e.g. logger.Info("task started", "task", key). Nil Logger discards all records
+type Logger struct {
+	sink   *sink
+	name   string
+	level  Level
+	fields []interface{}
+}
+
+// sink is shared by the logger and all loggers derived from it
+type sink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	format   Format
+	level    Level
+	packages map[string]Level
+	now      func() time.Time
+}
+
+// levelOf returns level of the package with the longest name matching name
+func (s *sink) levelOf(name string) Level {
+	for n := name; n != ""; {
+		if level, ok := s.packages[n]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return s.level
+}
+
+// Named returns logger of the package, e.g. influx. Names of nested loggers are joined with a dot
+func (l *Logger) Named(name string) *Logger {
+	if l == nil {
+		return nil
+	}
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &Logger{sink: l.sink, name: name, level: l.sink.levelOf(name), fields: l.fields}
+}
+
+// With returns logger adding keys and values to every record
+func (l *Logger) With(kv ...interface{}) *Logger {
+	if l == nil {
+		return nil
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(append(fields, l.fields...), kv...)
+	return &Logger{sink: l.sink, name: l.name, level: l.level, fields: fields}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level >= l.level
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(Debug, msg, kv)
+}
+
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(Info, msg, kv)
+}
+
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(Warn, msg, kv)
+}
+
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(Error, msg, kv)
+}
+
+// Fatal writes the record at Error level and exits the process with status 1
+func (l *Logger) Fatal(msg string, kv ...interface{}) {
+	l.log(Error, msg, kv)
+	os.Exit(1)
+}
+
+// Writer returns io.Writer logging every line as a message at the level, e.g. to redirect the standard logger
+func (l *Logger) Writer(level Level) io.Writer {
+	return lineWriter{l: l, level: level}
+}
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	r := record{time: l.sink.now(), level: level, name: l.name, msg: msg}
+	r.kv = make([]interface{}, 0, len(l.fields)+len(kv))
+	r.kv = append(append(r.kv, l.fields...), kv...)
+
+	buf := &bytes.Buffer{}
+	if l.sink.format == JSON {
+		r.json(buf)
+	} else {
+		r.logfmt(buf)
+	}
+	buf.WriteByte('\n')
+
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	_, _ = l.sink.w.Write(buf.Bytes())
+}
+
+type lineWriter struct {
+	l     *Logger
+	level Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.l.log(w.level, line, nil)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,121 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_Logfmt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newLogger(t, logging.Cfg{Output: buf}).Named("schedule").With("task", "SpeedTest")
+
+	logger.Info("starting task", "interval", time.Minute, "note", `say "hi"`, "err", errors.New("failed"), "odd")
+
+	expected := regexp.MustCompile(`^time=\S+ level=info logger=schedule msg="starting task" task=SpeedTest interval=1m0s note="say \\"hi\\"" err=failed odd=!MISSING\n$`)
+	if !expected.MatchString(buf.String()) {
+		t.Fatalf("unexpected record: %s", buf.String())
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newLogger(t, logging.Cfg{Output: buf, Format: logging.JSON}).Named("influx")
+
+	logger.Warn("writing point", "download", 300.5, "tags", map[string]string{"job": "home"})
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON: %s: %v", buf.String(), err)
+	}
+	if record["level"] != "warn" || record["logger"] != "influx" || record["msg"] != "writing point" || record["download"] != 300.5 {
+		t.Fatalf("unexpected record: %v", record)
+	}
+	if tags, ok := record["tags"].(map[string]interface{}); !ok || tags["job"] != "home" {
+		t.Fatalf("expected tags to be an object, actual: %v", record["tags"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, record["time"].(string)); err != nil {
+		t.Fatalf("invalid time: %v", err)
+	}
+}
+
+func TestLogger_Levels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	root := newLogger(t, logging.Cfg{
+		Output:   buf,
+		Level:    logging.Warn,
+		Packages: map[string]logging.Level{"influx": logging.Debug, "ookla.phases": logging.Error},
+	})
+
+	root.Info("root info")
+	root.Warn("root warn")
+	root.Named("influx").Debug("influx debug")
+	root.Named("influx").Named("retry").Debug("influx retry debug")
+	root.Named("ookla").Warn("ookla warn")
+	root.Named("ookla").Named("phases").Warn("ookla phases warn")
+
+	for _, expected := range []string{"root warn", "influx debug", "influx retry debug", "ookla warn"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q to be logged, actual: %s", expected, buf.String())
+		}
+	}
+	for _, unexpected := range []string{"root info", "ookla phases warn"} {
+		if strings.Contains(buf.String(), unexpected) {
+			t.Fatalf("expected %q to be skipped, actual: %s", unexpected, buf.String())
+		}
+	}
+}
+
+func TestLogger_Writer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	std := log.New(newLogger(t, logging.Cfg{Output: buf}).Named("stdlib").Writer(logging.Warn), "", 0)
+
+	std.Println("first line\nsecond line")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `level=warn logger=stdlib msg="first line"`) {
+		t.Fatalf("unexpected records: %v", lines)
+	}
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var logger *logging.Logger
+	logger.Named("influx").With("key", "value").Error("discarded")
+	if logger.Enabled(logging.Error) {
+		t.Fatal("expected nil logger to be disabled")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]logging.Level{"debug": logging.Debug, "INFO": logging.Info, "": logging.Info, "warning": logging.Warn, "error": logging.Error}
+	for s, expected := range tests {
+		level, err := logging.ParseLevel(s)
+		if err != nil || level != expected {
+			t.Fatalf("expected %q to be parsed as %v, actual: %v, %v", s, expected, level, err)
+		}
+	}
+	if _, err := logging.ParseLevel("trace"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestNew_UnsupportedFormat(t *testing.T) {
+	_, err := logging.New(logging.Cfg{Format: "xml"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func newLogger(t *testing.T, cfg logging.Cfg) *logging.Logger {
+	logger, err := logging.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// missingValue is logged for the key without a value
+const missingValue = "!MISSING"
+
+type record struct {
+	time  time.Time
+	level Level
+	name  string
+	msg   string
+	kv    []interface{}
+}
+
+// pairs calls f with the fixed fields of the record followed by pairs of kv
+func (r record) pairs(f func(key string, value interface{})) {
+	f("time", r.time.Format(time.RFC3339Nano))
+	f("level", r.level.String())
+	if r.name != "" {
+		f("logger", r.name)
+	}
+	f("msg", r.msg)
+	for i := 0; i < len(r.kv); i += 2 {
+		key := fmt.Sprint(r.kv[i])
+		var value interface{} = missingValue
+		if i+1 < len(r.kv) {
+			value = r.kv[i+1]
+		}
+		f(key, value)
+	}
+}
+
+func (r record) logfmt(buf *bytes.Buffer) {
+	first := true
+	r.pairs(func(key string, value interface{}) {
+		if !first {
+			buf.WriteByte(' ')
+		}
+		first = false
+		buf.WriteString(logfmtKey(key))
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(text(value)))
+	})
+}
+
+func (r record) json(buf *bytes.Buffer) {
+	buf.WriteByte('{')
+	first := true
+	r.pairs(func(key string, value interface{}) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(jsonValue(value))
+	})
+	buf.WriteByte('}')
+}
+
+// text formats values which are not encoded well by fmt or encoding/json
+func text(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func jsonValue(value interface{}) []byte {
+	b, err := json.Marshal(text(value))
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return b
+}
+
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	s := fmt.Sprint(value)
+	if s == "" || strings.IndexFunc(s, needsQuoting) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+}
@@ -0,0 +1,29 @@
+package observe
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"strconv"
+)
+
+const (
+	ErrorsCounterName = "speedtest_errors"
+)
+
+// ErrorHandler counts every error by its classification before passing it to the delegate
+func (m *Metrics) ErrorHandler(delegate core.ErrorHandler) *MetricsErrorHandler {
+	return &MetricsErrorHandler{delegate: delegate, metrics: m}
+}
+
+type MetricsErrorHandler struct {
+	delegate core.ErrorHandler
+	metrics  *Metrics
+}
+
+func (h *MetricsErrorHandler) Handle(err error) {
+	e := core.AsError(err)
+	if e == nil {
+		e = &core.Error{Phase: core.UnknownPhase, Kind: core.UnknownKind}
+	}
+	h.metrics.errors.WithLabelValues(string(e.Phase), string(e.Kind), strconv.FormatBool(e.Retryable), strconv.FormatBool(e.NetworkDown)).Inc()
+	h.delegate.Handle(err)
+}
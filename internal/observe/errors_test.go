@@ -0,0 +1,63 @@
+package observe_test
+
+import (
+	"context"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+func TestMetricsErrorHandler_Handle(t *testing.T) {
+	delegate := &countingHandler{}
+	registry := prometheus.NewRegistry()
+	handler := observe.NewMetrics(registry).ErrorHandler(delegate)
+
+	handler.Handle(core.Classify(core.DownloadPhase, context.DeadlineExceeded))
+	handler.Handle(core.Classify(core.DownloadPhase, context.DeadlineExceeded))
+	handler.Handle(errors.New("unclassified"))
+
+	if delegate.i != 3 {
+		t.Fatalf("expected 3 errors passed to delegate, actual: %d", delegate.i)
+	}
+
+	counts := countErrors(t, registry)
+	if counts["download test/timeout"] != 2 {
+		t.Errorf("expected 2 download timeouts, actual: %v", counts["download test/timeout"])
+	}
+	if counts["unknown/unknown"] != 1 {
+		t.Errorf("expected 1 unknown error, actual: %v", counts["unknown/unknown"])
+	}
+}
+
+// countErrors returns errors counter values by phase/kind
+func countErrors(t *testing.T, g prometheus.Gatherer) map[string]float64 {
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != observe.ErrorsCounterName {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			counts[labels["phase"]+"/"+labels["kind"]] += m.GetCounter().GetValue()
+		}
+	}
+	return counts
+}
+
+type countingHandler struct {
+	i int
+}
+
+func (h *countingHandler) Handle(error) {
+	h.i++
+}
@@ -0,0 +1,150 @@
+package observe
+
+import (
+	"context"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/syncfloat64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	"go.opentelemetry.io/otel/metric/unit"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	selector "go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"time"
+)
+
+const (
+	DownloadHistogramName = "speedtest_download"
+	UploadHistogramName   = "speedtest_upload"
+	PingHistogramName     = "speedtest_ping"
+)
+
+// OTLPProtocol is the transport of OTLP exporter
+type OTLPProtocol string
+
+const (
+	OTLPHTTP OTLPProtocol = "http"
+	OTLPGRPC OTLPProtocol = "grpc"
+)
+
+type OTLPConfig struct {
+	Protocol OTLPProtocol
+	// Endpoint is host and port of OTLP collector. Default endpoint of the protocol is used when empty
+	Endpoint string
+	// Insecure disables TLS
+	Insecure bool
+	// Headers are sent with every request, e.g. to authenticate
+	Headers map[string]string
+	// Interval between exports is 10 seconds when zero
+	Interval time.Duration
+	// ServiceName is speedtest when empty
+	ServiceName string
+}
+
+// ExportOTLP starts controller collecting metrics of its meters and pushing them to OTLP collector every interval.
+// Controller must be stopped to push the last metrics
+func ExportOTLP(ctx context.Context, cfg OTLPConfig) (*controller.Controller, error) {
+	var client otlpmetric.Client
+	switch cfg.Protocol {
+	case OTLPHTTP:
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		client = otlpmetrichttp.NewClient(opts...)
+	case OTLPGRPC:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		client = otlpmetricgrpc.NewClient(opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol: %s", cfg.Protocol)
+	}
+
+	exporter, err := otlpmetric.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP metrics exporter: %w", err)
+	}
+	name := cfg.ServiceName
+	if name == "" {
+		name = "speedtest"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(name)))
+	if err != nil {
+		return nil, fmt.Errorf("could not create metrics resource: %w", err)
+	}
+
+	opts := []controller.Option{controller.WithExporter(exporter), controller.WithResource(res)}
+	if cfg.Interval > 0 {
+		opts = append(opts, controller.WithCollectPeriod(cfg.Interval))
+	}
+	c := controller.New(processor.NewFactory(selector.NewWithHistogramDistribution(), exporter), opts...)
+	if err := c.Start(ctx); err != nil {
+		return nil, fmt.Errorf("could not start OTLP metrics controller: %w", err)
+	}
+	return c, nil
+}
+
+// OTelMetrics records pushes and measurements published on the bus with OpenTelemetry instruments. Every metric
+// has attribute of the job. Outage, queue and error metrics are recorded only by Metrics of Prometheus
+type OTelMetrics struct {
+	successfulPushes, failedPushes syncint64.Counter
+	download, upload, ping         syncfloat64.Histogram
+}
+
+func NewOTelMetrics(provider metric.MeterProvider) (*OTelMetrics, error) {
+	meter := provider.Meter("github.com/paluszkiewiczB/speedtest/internal/observe")
+	m := &OTelMetrics{}
+	var err error
+	if m.successfulPushes, err = meter.SyncInt64().Counter(SuccessfulPushesCounterName,
+		instrument.WithDescription("Number of successful pushes of speed measurements to storage")); err != nil {
+		return nil, err
+	}
+	if m.failedPushes, err = meter.SyncInt64().Counter(FailedPushesCounterName,
+		instrument.WithDescription("Number of failed pushes of speed measurements to storage")); err != nil {
+		return nil, err
+	}
+	if m.download, err = meter.SyncFloat64().Histogram(DownloadHistogramName,
+		instrument.WithDescription("Measured download speed in Mbit/s"), instrument.WithUnit("Mbit/s")); err != nil {
+		return nil, err
+	}
+	if m.upload, err = meter.SyncFloat64().Histogram(UploadHistogramName,
+		instrument.WithDescription("Measured upload speed in Mbit/s"), instrument.WithUnit("Mbit/s")); err != nil {
+		return nil, err
+	}
+	if m.ping, err = meter.SyncFloat64().Histogram(PingHistogramName,
+		instrument.WithDescription("Measured latency in milliseconds"), instrument.WithUnit(unit.Milliseconds)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *OTelMetrics) Notify(e core.Event) {
+	ctx := context.Background()
+	switch e := e.(type) {
+	case core.ResultProduced:
+		job := attribute.String("job", e.Job)
+		m.download.Record(ctx, e.Speed.Download, job)
+		m.upload.Record(ctx, e.Speed.Upload, job)
+		m.ping.Record(ctx, float64(e.Speed.Ping)/float64(time.Millisecond), job)
+	case core.PushSucceeded:
+		m.successfulPushes.Add(ctx, 1, attribute.String("job", e.Job))
+	case core.PushFailed:
+		m.failedPushes.Add(ctx, 1, attribute.String("job", e.Job))
+	}
+}
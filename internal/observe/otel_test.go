@@ -0,0 +1,66 @@
+package observe_test
+
+import (
+	"context"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metrictest"
+	"testing"
+	"time"
+)
+
+func TestOTelMetrics(t *testing.T) {
+	provider, exporter := metrictest.NewTestMeterProvider()
+	m, err := observe.NewOTelMetrics(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := core.NewBus()
+	bus.Subscribe(m)
+	speed := core.Speed{Download: 100, Upload: 20, Ping: 15 * time.Millisecond}
+	bus.Publish(core.ResultProduced{Job: "home", Speed: speed})
+	bus.Publish(core.PushSucceeded{Job: "home", Speed: speed})
+	bus.Publish(core.PushFailed{Job: "home", Speed: speed, Err: errors.New("unreachable")})
+	bus.Publish(core.PushFailed{Job: "home", Speed: speed, Err: errors.New("unreachable")})
+
+	if err := exporter.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	job := []attribute.KeyValue{attribute.String("job", "home")}
+	sums := map[string]float64{
+		observe.SuccessfulPushesCounterName: 1,
+		observe.FailedPushesCounterName:     2,
+	}
+	for name, want := range sums {
+		r, err := exporter.GetByNameAndAttributes(name, job)
+		if err != nil {
+			t.Fatalf("missing metric: %s: %s", name, err)
+		}
+		if got := r.Sum.CoerceToFloat64(r.NumberKind); got != want {
+			t.Errorf("expected %s = %v, actual: %v", name, want, got)
+		}
+	}
+	histograms := map[string]float64{
+		observe.DownloadHistogramName: 100,
+		observe.UploadHistogramName:   20,
+		observe.PingHistogramName:     15,
+	}
+	for name, want := range histograms {
+		r, err := exporter.GetByNameAndAttributes(name, job)
+		if err != nil {
+			t.Fatalf("missing metric: %s: %s", name, err)
+		}
+		if got := r.Sum.CoerceToFloat64(r.NumberKind); r.Count != 1 || got != want {
+			t.Errorf("expected single %s of %v, actual count: %d, sum: %v", name, want, r.Count, got)
+		}
+	}
+}
+
+func TestExportOTLP_UnsupportedProtocol(t *testing.T) {
+	if _, err := observe.ExportOTLP(context.Background(), observe.OTLPConfig{Protocol: "udp"}); err == nil {
+		t.Fatal("expected error of unsupported protocol")
+	}
+}
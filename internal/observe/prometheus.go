@@ -1,33 +1,71 @@
 package observe
 
 import (
-	"context"
-	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"log"
 	"net/http"
 )
 
-func ExposePrometheus(ctx context.Context, cfg PrometheusConfig) {
-	http.Handle(cfg.Endpoint, promhttp.Handler())
-	server := http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: nil}
-	go func() {
-		err := server.ListenAndServe()
-		if err != nil {
-			log.Printf("could not start prometheus server: %s\n", err)
-		}
-	}()
+// NewRegistry creates registry with collectors of the Go runtime and the process, like the default registry of
+// Prometheus
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return registry
+}
+
+// Handler serves metrics gathered by g in Prometheus exposition format
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
 
-	go func() {
-		<-ctx.Done()
-		err := server.Close()
-		if err != nil {
-			log.Printf("could not stop prometheus http server")
-		}
-	}()
+// Metrics of the speed test, registered in a single registry. They are recorded by decorators created by Metrics
+type Metrics struct {
+	successfulPushes, failedPushes prometheus.Counter
+	networkUp                      prometheus.Gauge
+	outages, downtime              prometheus.Counter
+	errors                         *prometheus.CounterVec
+	queueDepth                     prometheus.Gauge
+	queueDrops                     prometheus.Counter
 }
 
-type PrometheusConfig struct {
-	Endpoint string
-	Port     int
+// NewMetrics registers metrics in the registerer. It panics when they are already registered
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	f := promauto.With(registerer)
+	return &Metrics{
+		successfulPushes: f.NewCounter(prometheus.CounterOpts{
+			Name: SuccessfulPushesCounterName,
+			Help: "Number of successful pushes of speed measurements to storage",
+		}),
+		failedPushes: f.NewCounter(prometheus.CounterOpts{
+			Name: FailedPushesCounterName,
+			Help: "Number of failed pushes of speed measurements to storage",
+		}),
+		networkUp: f.NewGauge(prometheus.GaugeOpts{
+			Name: NetworkUpGaugeName,
+			Help: "1 when network is reachable, 0 during outage",
+		}),
+		outages: f.NewCounter(prometheus.CounterOpts{
+			Name: OutagesCounterName,
+			Help: "Number of finished outages",
+		}),
+		downtime: f.NewCounter(prometheus.CounterOpts{
+			Name: DowntimeCounterName,
+			Help: "Total duration of finished outages in seconds",
+		}),
+		errors: f.NewCounterVec(prometheus.CounterOpts{
+			Name: ErrorsCounterName,
+			Help: "Number of errors handled, partitioned by failed phase and kind of the cause",
+		}, []string{"phase", "kind", "retryable", "network_down"}),
+		queueDepth: f.NewGauge(prometheus.GaugeOpts{
+			Name: QueueDepthGaugeName,
+			Help: "Number of speed test results waiting for storage",
+		}),
+		queueDrops: f.NewCounter(prometheus.CounterOpts{
+			Name: QueueDropsCounterName,
+			Help: "Number of speed test results dropped, because the queue was full",
+		}),
+	}
 }
@@ -1,68 +1,42 @@
 package observe_test
 
 import (
-	"context"
-	"fmt"
 	"github.com/paluszkiewiczB/speedtest/internal/observe"
-	"log"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
-func TestExposePrometheus(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
+func TestHandler(t *testing.T) {
+	t.Run("should expose metrics of the registry and the runtime", func(t *testing.T) {
+		registry := observe.NewRegistry()
+		observe.NewMetrics(registry)
 
-	t.Run("should return 200 when Prometheus endpoint is exposed", func(t *testing.T) {
-		cfg, url := prepareCfg()
-		ctx, cancel := context.WithCancel(context.Background())
-		t.Cleanup(func() {
-			cancel()
-		})
-		observe.ExposePrometheus(ctx, cfg)
-		get, err := http.Get(url)
-		if get.StatusCode != 200 {
-			log.Fatalf("expected status 200, actual: %d", get.StatusCode)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-	})
-
-	t.Run("should stop Prometheus endpoint when context gets cancelled", func(t *testing.T) {
-		cfg, url := prepareCfg()
-		ctx, cancel := context.WithCancel(context.Background())
-		t.Cleanup(func() {
-			cancel()
-		})
-		observe.ExposePrometheus(ctx, cfg)
-		get, err := http.Get(url)
-		if get.StatusCode != 200 {
-			t.Fatalf("expected status code 200, actual: %d", get.StatusCode)
+		w := httptest.NewRecorder()
+		observe.Handler(registry).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code 200, actual: %d", w.Code)
 		}
+		body, err := ioutil.ReadAll(w.Body)
 		if err != nil {
 			t.Fatal(err)
 		}
-		cancel()
-		_, err = http.Get(url)
-		if err == nil {
-			t.Fatal("expected error, but it is nil")
+		for _, name := range []string{observe.SuccessfulPushesCounterName, observe.NetworkUpGaugeName, "go_goroutines"} {
+			if !strings.Contains(string(body), name) {
+				t.Errorf("expected metric: %s", name)
+			}
 		}
 	})
-}
 
-// prepareCfg returns PrometheusConfig and url to endpoint
-func prepareCfg() (observe.PrometheusConfig, string) {
-	port, err := getFreePort()
-	if err != nil {
-		panic(err)
-	}
-	endpoint := fmt.Sprintf("/test%d", port)
-	cfg := observe.PrometheusConfig{
-		Endpoint: endpoint,
-		Port:     port,
-	}
-	url := fmt.Sprintf("http://localhost:%d/%s", port, endpoint)
-	return cfg, url
+	t.Run("should not share metrics between registries", func(t *testing.T) {
+		first, second := observe.NewRegistry(), observe.NewRegistry()
+		observe.NewMetrics(first).QueueObserver().QueueDepth(5)
+		observe.NewMetrics(second)
+
+		if depth := gatherValue(t, second, observe.QueueDepthGaugeName); depth != 0 {
+			t.Fatalf("expected empty queue in the second registry, actual: %v", depth)
+		}
+	})
 }
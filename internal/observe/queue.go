@@ -0,0 +1,27 @@
+package observe
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+)
+
+const (
+	QueueDepthGaugeName   = "speedtest_result_queue_depth"
+	QueueDropsCounterName = "speedtest_result_queue_drops"
+)
+
+// QueueObserver exports state of the queue of results as metrics
+func (m *Metrics) QueueObserver() *MetricsQueueObserver {
+	return &MetricsQueueObserver{metrics: m}
+}
+
+type MetricsQueueObserver struct {
+	metrics *Metrics
+}
+
+func (o *MetricsQueueObserver) QueueDepth(depth int) {
+	o.metrics.queueDepth.Set(float64(depth))
+}
+
+func (o *MetricsQueueObserver) ResultDropped(core.Speed) {
+	o.metrics.queueDrops.Inc()
+}
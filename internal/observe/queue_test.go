@@ -0,0 +1,25 @@
+package observe_test
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+func TestMetricsQueueObserver(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	observer := observe.NewMetrics(registry).QueueObserver()
+	drops := gatherValue(t, registry, observe.QueueDropsCounterName)
+
+	observer.QueueDepth(3)
+	observer.ResultDropped(core.InvalidSpeed)
+	observer.ResultDropped(core.InvalidSpeed)
+
+	if depth := gatherValue(t, registry, observe.QueueDepthGaugeName); depth != 3 {
+		t.Fatalf("expected queue depth: 3, actual: %v", depth)
+	}
+	if d := gatherValue(t, registry, observe.QueueDropsCounterName) - drops; d != 2 {
+		t.Fatalf("expected 2 dropped results, actual: %v", d)
+	}
+}
@@ -2,47 +2,68 @@ package observe
 
 import (
 	"context"
+	"fmt"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
 )
 
 const (
 	SuccessfulPushesCounterName = "speedtest_successful_storage_pushes"
 	FailedPushesCounterName     = "speedtest_failed_storage_pushes"
+	NetworkUpGaugeName          = "speedtest_network_up"
+	OutagesCounterName          = "speedtest_outages"
+	DowntimeCounterName         = "speedtest_downtime_seconds"
 )
 
-var (
-	successfulPushes = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "speedtest_successful_storage_pushes",
-		Help: "Number of successful pushes of speed measurements to storage",
-	})
-	failedPushes = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "speedtest_failed_storage_pushes",
-		Help: "Number of failed pushes of speed measurements to storage",
-	})
-)
-
-func Storage(delegate core.Storage) *MetricsStorage {
+// Storage counts pushes to the delegate and records outages
+func (m *Metrics) Storage(delegate core.Storage) *MetricsStorage {
+	m.networkUp.Set(1)
 	return &MetricsStorage{
 		delegate: delegate,
+		metrics:  m,
 	}
 }
 
 type MetricsStorage struct {
 	delegate core.Storage
+	metrics  *Metrics
 }
 
 func (s *MetricsStorage) Push(ctx context.Context, speed core.Speed) error {
 	err := s.delegate.Push(ctx, speed)
 	if err == nil {
-		successfulPushes.Inc()
+		s.metrics.successfulPushes.Inc()
 	} else {
-		failedPushes.Inc()
+		s.metrics.failedPushes.Inc()
 	}
 	return err
 }
 
+// PushOutage records outage metrics and pushes the outage to the delegate, if it supports outages
+func (s *MetricsStorage) PushOutage(ctx context.Context, outage core.Outage) error {
+	if outage.Ongoing() {
+		s.metrics.networkUp.Set(0)
+	} else {
+		s.metrics.networkUp.Set(1)
+		s.metrics.outages.Inc()
+		s.metrics.downtime.Add(outage.End.Sub(outage.Start).Seconds())
+	}
+
+	if o, ok := s.delegate.(core.OutageStorage); ok {
+		return o.PushOutage(ctx, outage)
+	}
+	return nil
+}
+
+// Query reads speeds from the delegate, if it is core.Queryable
+func (s *MetricsStorage) Query(ctx context.Context, from, to time.Time) ([]core.Speed, error) {
+	q, ok := s.delegate.(core.Queryable)
+	if !ok {
+		return nil, fmt.Errorf("storage is not queryable: %T", s.delegate)
+	}
+	return q.Query(ctx, from, to)
+}
+
 func (s *MetricsStorage) Close() error {
 	return s.delegate.Close()
 }
@@ -3,12 +3,13 @@ package observe_test
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/google/go-cmp/cmp"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
 	"github.com/paluszkiewiczB/speedtest/internal/observe"
+	"github.com/prometheus/client_golang/prometheus"
 	"io/ioutil"
-	"net"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
@@ -21,45 +22,34 @@ var (
 )
 
 func TestMetricsStorage_Push(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
-
 	successCount, failureCount := 7, 12
 	speeds := prepareSpeeds(successCount, failureCount)
 
-	s := failingStorage{}
-	storage := observe.Storage(s)
+	registry := prometheus.NewRegistry()
+	storage := observe.NewMetrics(registry).Storage(failingStorage{})
 	ctx := context.Background()
-	port, err := getFreePort()
-	if err != nil {
-		panic(err)
-	}
-	cfg := observe.PrometheusConfig{
-		Endpoint: "/testMetrics",
-		Port:     port,
+	server := httptest.NewServer(observe.Handler(registry))
+	defer server.Close()
+
+	for _, s := range speeds {
+		_ = storage.Push(ctx, s)
 	}
-	withPrometheusEndpoint(ctx, cfg, func() {
-		for _, s := range speeds {
-			_ = storage.Push(ctx, s)
-		}
 
-		sCount, fCount := checkMetrics(cfg)
+	sCount, fCount := checkMetrics(server.URL)
 
-		if successCount != sCount {
-			t.Errorf("Expected: %d successes, got: %d", successCount, sCount)
-		}
+	if successCount != sCount {
+		t.Errorf("Expected: %d successes, got: %d", successCount, sCount)
+	}
 
-		if failureCount != fCount {
-			t.Errorf("Expected: %d failures, got: %d", failureCount, fCount)
-		}
-	})
+	if failureCount != fCount {
+		t.Errorf("Expected: %d failures, got: %d", failureCount, fCount)
+	}
 }
 
 func TestMetricsStorage_Close(t *testing.T) {
 	t.Run("should close delegate storage", func(t *testing.T) {
 		delegate := &closeCountingStorage{}
-		storage := observe.Storage(delegate)
+		storage := observe.NewMetrics(prometheus.NewRegistry()).Storage(delegate)
 		err := storage.Close()
 		if err != nil {
 			t.Fatal(err)
@@ -70,7 +60,7 @@ func TestMetricsStorage_Close(t *testing.T) {
 	})
 
 	t.Run("should return error if delegate returns error on Close", func(t *testing.T) {
-		storage := observe.Storage(failingStorage{})
+		storage := observe.NewMetrics(prometheus.NewRegistry()).Storage(failingStorage{})
 		err := storage.Close()
 		if err == nil {
 			t.Fatalf("expected error when closing storage, but it is nil")
@@ -89,15 +79,8 @@ func prepareSpeeds(successCount int, failureCount int) []core.Speed {
 	return speeds
 }
 
-func withPrometheusEndpoint(c context.Context, cfg observe.PrometheusConfig, f func()) {
-	ctx, cancel := context.WithCancel(c)
-	defer cancel()
-	observe.ExposePrometheus(ctx, cfg)
-	f()
-}
-
-func checkMetrics(config observe.PrometheusConfig) (int, int) {
-	response, err := http.Get(fmt.Sprintf("http://localhost:%d%s", config.Port, config.Endpoint))
+func checkMetrics(url string) (int, int) {
+	response, err := http.Get(url)
 	if err != nil {
 		panic(err)
 	}
@@ -133,7 +116,7 @@ type failingStorage struct {
 }
 
 func (f failingStorage) Push(_ context.Context, speed core.Speed) error {
-	if speed == success {
+	if cmp.Equal(speed, success) {
 		return nil
 	}
 	return errors.New("error")
@@ -143,29 +126,75 @@ func (f failingStorage) Close() error {
 	return errors.New("error")
 }
 
-func getFreePort() (int, error) {
-	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+type closeCountingStorage struct {
+	counter int
+}
+
+func (c *closeCountingStorage) Push(ctx context.Context, speed core.Speed) error {
+	panic("implement me")
+}
+
+func (c *closeCountingStorage) Close() error {
+	c.counter++
+	return nil
+}
+
+func TestMetricsStorage_PushOutage(t *testing.T) {
+	delegate := &outageStorage{}
+	registry := prometheus.NewRegistry()
+	storage := observe.NewMetrics(registry).Storage(delegate)
+	ctx := context.Background()
+	start := time.Unix(100, 0)
+
+	err := storage.PushOutage(ctx, core.Outage{Start: start})
 	if err != nil {
-		return 0, err
+		t.Fatal(err)
+	}
+	if up := gatherValue(t, registry, observe.NetworkUpGaugeName); up != 0 {
+		t.Fatalf("expected network to be down, actual: %v", up)
 	}
 
-	l, err := net.ListenTCP("tcp", addr)
+	before := gatherValue(t, registry, observe.DowntimeCounterName)
+	err = storage.PushOutage(ctx, core.Outage{Start: start, End: start.Add(90 * time.Second)})
 	if err != nil {
-		return 0, err
+		t.Fatal(err)
+	}
+	if up := gatherValue(t, registry, observe.NetworkUpGaugeName); up != 1 {
+		t.Fatalf("expected network to be up, actual: %v", up)
+	}
+	if d := gatherValue(t, registry, observe.DowntimeCounterName) - before; d != 90 {
+		t.Fatalf("expected downtime to grow by 90 seconds, actual: %v", d)
+	}
+	if len(delegate.o) != 2 {
+		t.Fatalf("expected 2 outages pushed to delegate, actual: %d", len(delegate.o))
 	}
-	defer l.Close()
-	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
-type closeCountingStorage struct {
-	counter int
+func gatherValue(t *testing.T, g prometheus.Gatherer, name string) float64 {
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		m := f.GetMetric()[0]
+		if m.GetGauge() != nil {
+			return m.GetGauge().GetValue()
+		}
+		return m.GetCounter().GetValue()
+	}
+	t.Fatalf("metric not found: %s", name)
+	return 0
 }
 
-func (c *closeCountingStorage) Push(ctx context.Context, speed core.Speed) error {
-	panic("implement me")
+type outageStorage struct {
+	failingStorage
+	o []core.Outage
 }
 
-func (c *closeCountingStorage) Close() error {
-	c.counter++
+func (s *outageStorage) PushOutage(_ context.Context, outage core.Outage) error {
+	s.o = append(s.o, outage)
 	return nil
 }
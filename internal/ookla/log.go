@@ -2,33 +2,61 @@ package ookla
 
 import (
 	"github.com/google/uuid"
-	"log"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"sync"
 	"time"
 )
 
+// Logging logs every phase of the speed test. Tester gets its own bus forwarding events to the previous one, so
+// other testers sharing that bus are not logged
 func Logging(tester *SpeedTester) *SpeedTester {
-	logActions(tester.actions)
+	shared := tester.bus
+	tester.bus = core.NewBus()
+	tester.bus.Subscribe(newPhaseLogger(tester.logger.Named("phases")))
+	tester.bus.Subscribe(core.SubscriberFunc(shared.Publish))
 	return tester
 }
 
-func logActions(actions chan action) {
-	go func() {
-		for a := range actions {
-			go func(a action) {
-				log.Printf("[%s] speed test started action: %s at: %v\n", a.id, a.name, a.start)
-				dots(a.id, a.name, a.finished)
-			}(a)
+func newPhaseLogger(logger *logging.Logger) *phaseLogger {
+	return &phaseLogger{running: make(map[uuid.UUID]chan struct{}), logger: logger}
+}
+
+// phaseLogger logs a record when the phase starts and then every 5 seconds, until the phase is finished
+type phaseLogger struct {
+	mu      sync.Mutex
+	running map[uuid.UUID]chan struct{}
+	logger  *logging.Logger
+}
+
+func (l *phaseLogger) Notify(e core.Event) {
+	switch e := e.(type) {
+	case core.PhaseStarted:
+		finished := make(chan struct{})
+		l.mu.Lock()
+		l.running[e.ID] = finished
+		l.mu.Unlock()
+		l.logger.Info("speed test phase started", "id", e.ID, "job", e.Job, "phase", e.Phase, "start", e.Start)
+		go l.progress(e.ID, e.Job, e.Phase, finished)
+	case core.PhaseFinished:
+		l.mu.Lock()
+		finished, ok := l.running[e.ID]
+		delete(l.running, e.ID)
+		l.mu.Unlock()
+		if ok {
+			close(finished)
 		}
-	}()
+		l.logger.Info("speed test phase finished", "id", e.ID, "job", e.Job, "phase", e.Phase, "duration", e.Duration, "err", e.Err)
+	}
 }
 
-func dots(id uuid.UUID, name string, stop <-chan struct{}) {
+func (l *phaseLogger) progress(id uuid.UUID, job string, phase core.Phase, stop <-chan struct{}) {
 	timer := time.NewTicker(5 * time.Second)
 	defer timer.Stop()
 	for {
 		select {
 		case <-timer.C:
-			log.Printf("[%s] %s...\n", id, name)
+			l.logger.Info("speed test phase in progress", "id", id, "job", job, "phase", phase)
 		case <-stop:
 			return
 		}
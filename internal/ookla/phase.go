@@ -0,0 +1,17 @@
+package ookla
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"time"
+)
+
+const (
+	UserInfoPhase   core.Phase = "fetching user info"
+	ServerListPhase core.Phase = "fetching server list"
+	ServerPhase     core.Phase = "finding server"
+)
+
+// Timeouts limits duration of every phase of the speed test. Zero value means no limit
+type Timeouts struct {
+	UserInfo, ServerList, Ping, Download, Upload time.Duration
+}
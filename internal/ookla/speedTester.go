@@ -2,63 +2,107 @@ package ookla
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/resilience"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
 	"github.com/showwin/speedtest-go/speedtest"
-	"log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"net/http"
 	"time"
 )
 
-func NewSpeedTester() *SpeedTester {
-	actions := make(chan action)
-	return &SpeedTester{actions: actions}
+func NewSpeedTester(cfg Cfg) *SpeedTester {
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	bus := cfg.Bus
+	if bus == nil {
+		bus = core.NewBus()
+	}
+	return &SpeedTester{
+		timeouts: cfg.Timeouts,
+		client:   speedtest.New(speedtest.WithDoer(client)),
+		bus:      bus,
+		logger:   logging.For(cfg.Logger, "ookla"),
+	}
+}
+
+type Cfg struct {
+	Timeouts Timeouts
+	// Client sends all requests of the speed test, http.DefaultClient is used when nil
+	Client *http.Client
+	// Bus receives start and end of every phase. Tester has its own bus when nil
+	Bus *core.Bus
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
 }
 
 type SpeedTester struct {
-	actions chan action
+	timeouts Timeouts
+	client   *speedtest.Speedtest
+	bus      *core.Bus
+	logger   *logging.Logger
 }
 
-func (t *SpeedTester) Test(ctx context.Context) (core.Speed, error) {
-	user, err := speedtest.FetchUserInfo()
+const instrumentation = "github.com/paluszkiewiczB/speedtest/internal/ookla"
+
+// Test measures the speed. It is traced with the span of every phase
+func (t *SpeedTester) Test(ctx context.Context) (speed core.Speed, err error) {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, "ookla.test")
+	defer func() { tracing.End(span, err) }()
+
+	var user *speedtest.User
+	err = t.test(ctx, UserInfoPhase, t.timeouts.UserInfo, func(ctx context.Context) error {
+		var err error
+		user, err = t.client.FetchUserInfoContext(ctx)
+		return err
+	})
 	if err != nil {
-		log.Println("fetching user info failed")
 		return core.InvalidSpeed, err
 	}
-	serverList, err := speedtest.FetchServerList(user)
+
+	var serverList speedtest.Servers
+	err = t.test(ctx, ServerListPhase, t.timeouts.ServerList, func(ctx context.Context) error {
+		var err error
+		serverList, err = t.client.FetchServerListContext(ctx, user)
+		return err
+	})
 	if err != nil {
-		log.Println("fetching server list failed")
 		return core.InvalidSpeed, err
 	}
+
 	targets, err := serverList.FindServer([]int{})
 	if err != nil {
-		log.Println("finding server failed")
-		return core.InvalidSpeed, err
+		return core.InvalidSpeed, core.Classify(ServerPhase, err)
 	}
 	server := targets[0]
-	log.Printf("selected server:  %8.2fkm %s (%s)\n", server.Distance, server.Name, server.Country)
+	t.logger.Info("selected server", "server", server.Name, "country", server.Country, "distance_km", server.Distance)
+	span.SetAttributes(attribute.String("server.name", server.Name), attribute.String("server.country", server.Country),
+		attribute.Float64("server.distance_km", server.Distance))
 
 	measurementTime := time.Now()
-	err = t.test(ctx, "ping test", func() error {
-		return server.PingTest()
+	err = t.test(ctx, core.PingPhase, t.timeouts.Ping, func(ctx context.Context) error {
+		return server.PingTestContext(ctx)
 	})
 	if err != nil {
 		return core.InvalidSpeed, err
 	}
 
-	err = t.test(ctx, "download test", func() error {
+	err = t.test(ctx, core.DownloadPhase, t.timeouts.Download, func(ctx context.Context) error {
 		return server.DownloadTestContext(ctx, false)
 	})
 	if err != nil {
-		log.Println("download test failed")
 		return core.InvalidSpeed, err
 	}
 
-	err = t.test(ctx, "upload test", func() error {
-		return server.UploadTest(false)
+	err = t.test(ctx, core.UploadPhase, t.timeouts.Upload, func(ctx context.Context) error {
+		return server.UploadTestContext(ctx, false)
 	})
 	if err != nil {
-		fmt.Println("upload test failed")
 		return core.InvalidSpeed, err
 	}
 
@@ -70,37 +114,30 @@ func (t *SpeedTester) Test(ctx context.Context) (core.Speed, error) {
 	}, nil
 }
 
-func (t *SpeedTester) test(ctx context.Context, name string, test func() error) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return context.Canceled
-		default:
-			actionFinished := make(chan struct{}, 1)
-			t.actions <- newAction(name, actionFinished)
-			err := test()
-			if err != nil {
-				log.Printf("%s failed\n", name)
-				return err
-			}
-			actionFinished <- struct{}{}
-			return err
-		}
+// test runs single phase of the speed test. Phase is limited by max duration, unless max is not positive.
+// Returned error is always *core.Error
+func (t *SpeedTester) test(ctx context.Context, phase core.Phase, max time.Duration, test func(context.Context) error) error {
+	if ctx.Err() != nil {
+		return core.Classify(phase, ctx.Err())
 	}
-}
 
-func newAction(name string, finished <-chan struct{}) action {
-	return action{
-		id:       uuid.New(),
-		name:     name,
-		start:    time.Now(),
-		finished: finished,
-	}
-}
+	id, start := uuid.New(), time.Now()
+	job := core.JobName(ctx)
+	t.bus.Publish(core.PhaseStarted{ID: id, Job: job, Phase: phase, Start: start})
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, string(phase))
 
-type action struct {
-	id       uuid.UUID
-	name     string
-	start    time.Time
-	finished <-chan struct{}
+	var err error
+	if max > 0 {
+		err = resilience.Timeout(ctx, max, test)
+	} else {
+		err = test(ctx)
+	}
+	// timeout of the phase may fire together with cancellation of the test, which must not be retried as timeout
+	if err != nil && ctx.Err() != nil {
+		err = core.Classify(phase, ctx.Err())
+	}
+	err = core.Classify(phase, err)
+	tracing.End(span, err)
+	t.bus.Publish(core.PhaseFinished{ID: id, Job: job, Phase: phase, Start: start, Duration: time.Since(start), Err: err})
+	return err
 }
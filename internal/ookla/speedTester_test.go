@@ -0,0 +1,135 @@
+package ookla_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/ookla"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSpeedTester_Test(t *testing.T) {
+	t.Run("should classify errors of the phase", func(t *testing.T) {
+		tests := map[string]struct {
+			roundTrip func(cancel context.CancelFunc) roundTripper
+			kind      core.Kind
+			retryable bool
+		}{
+			"when phase times out": {
+				roundTrip: func(context.CancelFunc) roundTripper {
+					return func(*http.Request) (*http.Response, error) {
+						return nil, context.DeadlineExceeded
+					}
+				},
+				kind:      core.TimeoutKind,
+				retryable: true,
+			},
+			"when test is cancelled together with timeout of the phase": {
+				roundTrip: func(cancel context.CancelFunc) roundTripper {
+					return func(*http.Request) (*http.Response, error) {
+						cancel()
+						return nil, context.DeadlineExceeded
+					}
+				},
+				kind: core.CancelledKind,
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				tester := ookla.NewSpeedTester(ookla.Cfg{Client: &http.Client{Transport: tt.roundTrip(cancel)}})
+
+				_, err := tester.Test(ctx)
+				e := core.AsError(err)
+				if e == nil {
+					t.Fatalf("expected *core.Error, actual: %v", err)
+				}
+				if e.Phase != ookla.UserInfoPhase || e.Kind != tt.kind || e.Retryable != tt.retryable {
+					t.Fatalf("expected %s error of phase: %s, retryable: %v, actual: %+v", tt.kind, ookla.UserInfoPhase, tt.retryable, e)
+				}
+			})
+		}
+	})
+}
+
+func TestSpeedTester_TestBlockedPhase(t *testing.T) {
+	tests := map[string]struct {
+		timeouts ookla.Timeouts
+		cancelIn time.Duration
+		kind     core.Kind
+	}{
+		"should stop phase after its timeout": {
+			timeouts: ookla.Timeouts{UserInfo: 20 * time.Millisecond},
+			kind:     core.TimeoutKind,
+		},
+		"should stop phase when test is cancelled": {
+			cancelIn: 20 * time.Millisecond,
+			kind:     core.CancelledKind,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bus := core.NewBus()
+			events := &recorder{}
+			bus.Subscribe(events)
+			tester := ookla.NewSpeedTester(ookla.Cfg{Timeouts: tt.timeouts, Client: &http.Client{Transport: blocking()}, Bus: bus})
+			ctx, cancel := context.WithCancel(core.WithJob(context.Background(), "fiber"))
+			defer cancel()
+			if tt.cancelIn > 0 {
+				time.AfterFunc(tt.cancelIn, cancel)
+			}
+
+			errC := make(chan error, 1)
+			go func() {
+				_, err := tester.Test(ctx)
+				errC <- err
+			}()
+			var err error
+			select {
+			case err = <-errC:
+			case <-time.After(time.Second):
+				t.Fatal("expected blocked phase to be stopped")
+			}
+
+			if e := core.AsError(err); e == nil || e.Phase != ookla.UserInfoPhase || e.Kind != tt.kind {
+				t.Fatalf("expected %s error of phase: %s, actual: %v", tt.kind, ookla.UserInfoPhase, err)
+			}
+			if len(events.events) != 2 {
+				t.Fatalf("expected start and end of the phase, actual: %v", events.events)
+			}
+			if started, ok := events.events[0].(core.PhaseStarted); !ok || started.Job != "fiber" {
+				t.Fatalf("expected started phase of job: fiber, actual: %v", events.events[0])
+			}
+			if finished, ok := events.events[1].(core.PhaseFinished); !ok || finished.Job != "fiber" || finished.Phase != ookla.UserInfoPhase || finished.Err == nil {
+				t.Fatalf("expected failed phase: %s of job: fiber, actual: %v", ookla.UserInfoPhase, events.events[1])
+			}
+		})
+	}
+}
+
+// blocking returns transport which blocks until the request is cancelled, like http.Transport waiting for response
+func blocking() roundTripper {
+	return func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	}
+}
+
+type recorder struct {
+	events []core.Event
+}
+
+func (r *recorder) Notify(e core.Event) {
+	r.events = append(r.events, e)
+}
+
+// roundTripper stubs the speedtest.net API
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,128 @@
+package outage
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"sync"
+	"time"
+)
+
+func NewMonitor(cfg Cfg, storage core.OutageStorage, errH core.ErrorHandler) *Monitor {
+	return &Monitor{cfg: cfg, storage: storage, errH: errH, now: time.Now, logger: logging.For(cfg.Logger, "outage")}
+}
+
+type Cfg struct {
+	Probes []Probe
+	// Timeout limits duration of single probe
+	Timeout time.Duration
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// Monitor detects outages with Probes. Network is considered down when all the Probes fail
+type Monitor struct {
+	cfg     Cfg
+	storage core.OutageStorage
+	errH    core.ErrorHandler
+	now     func() time.Time
+	logger  *logging.Logger
+
+	mu      sync.Mutex
+	current *core.Outage
+}
+
+// Check runs all the probes once. It starts a new outage when all of them failed and ends the ongoing one when any
+// of them succeeded. Both start and end of the outage are pushed to the storage
+func (m *Monitor) Check(ctx context.Context) {
+	up := m.probe(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	switch {
+	case !up && m.current == nil:
+		m.current = &core.Outage{Start: now}
+		m.logger.Warn("outage started", "start", now)
+		m.push(ctx, *m.current)
+	case up && m.current != nil:
+		m.current.End = now
+		m.logger.Info("outage ended", "start", m.current.Start, "end", now, "duration", m.current.Duration(now))
+		m.push(ctx, *m.current)
+		m.current = nil
+	}
+}
+
+// Stop ends the ongoing outage, if there is any, and pushes its end to the storage, so it is not left open when
+// the process exits. Monitor should not be checked after Stop
+func (m *Monitor) Stop(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return
+	}
+	now := m.now()
+	m.current.End = now
+	m.logger.Info("outage ended by shutdown", "start", m.current.Start, "end", now, "duration", m.current.Duration(now))
+	m.push(ctx, *m.current)
+	m.current = nil
+}
+
+// StopBefore returns storage, which stops the monitor before it is closed, so the end of the ongoing outage is pushed
+// to the storage
+func (m *Monitor) StopBefore(storage core.Storage) core.Storage {
+	return &stoppingStorage{Storage: storage, monitor: m}
+}
+
+type stoppingStorage struct {
+	core.Storage
+	monitor *Monitor
+}
+
+func (s *stoppingStorage) Close() error {
+	s.monitor.Stop(context.Background())
+	return s.Storage.Close()
+}
+
+// Current returns ongoing outage, if there is any
+func (m *Monitor) Current() (core.Outage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return core.Outage{}, false
+	}
+	return *m.current, true
+}
+
+func (m *Monitor) probe(ctx context.Context) bool {
+	results := make(chan error, len(m.cfg.Probes))
+	for _, p := range m.cfg.Probes {
+		go func(p Probe) {
+			probeCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+			defer cancel()
+			err := p.Probe(probeCtx)
+			if err != nil {
+				m.logger.Warn("probe failed", "probe", p, "err", err)
+			}
+			results <- err
+		}(p)
+	}
+
+	up := false
+	for range m.cfg.Probes {
+		if err := <-results; err == nil {
+			up = true
+		}
+	}
+	return up
+}
+
+func (m *Monitor) push(ctx context.Context, outage core.Outage) {
+	err := m.storage.PushOutage(ctx, outage)
+	if err != nil {
+		m.errH.Handle(core.Classify(core.StoragePhase, err))
+	}
+}
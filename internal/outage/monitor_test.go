@@ -0,0 +1,168 @@
+package outage_test
+
+import (
+	"context"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/dummy"
+	"github.com/paluszkiewiczB/speedtest/internal/outage"
+	"testing"
+	"time"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	t.Run("should not start outage when any probe succeeds", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		monitor := outage.NewMonitor(cfg(&probe{down: true}, &probe{}), storage, &countingHandler{})
+
+		monitor.Check(context.Background())
+
+		if _, ok := monitor.Current(); ok {
+			t.Fatal("expected no ongoing outage")
+		}
+		if len(storage.GetOutages()) != 0 {
+			t.Fatalf("expected no outages, actual: %v", storage.GetOutages())
+		}
+	})
+
+	t.Run("should push start and end of outage", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		first, second := &probe{down: true}, &probe{down: true}
+		monitor := outage.NewMonitor(cfg(first, second), storage, &countingHandler{})
+
+		monitor.Check(context.Background())
+		monitor.Check(context.Background())
+		if _, ok := monitor.Current(); !ok {
+			t.Fatal("expected ongoing outage")
+		}
+
+		second.down = false
+		monitor.Check(context.Background())
+		if _, ok := monitor.Current(); ok {
+			t.Fatal("expected outage to be finished")
+		}
+
+		outages := storage.GetOutages()
+		if len(outages) != 2 {
+			t.Fatalf("expected outage to be pushed twice, actual: %v", outages)
+		}
+		if !outages[0].Ongoing() {
+			t.Errorf("expected first pushed outage to be ongoing: %v", outages[0])
+		}
+		if outages[1].Ongoing() || outages[1].Start != outages[0].Start {
+			t.Errorf("expected second pushed outage to be the finished first one: %v", outages[1])
+		}
+	})
+
+	t.Run("should treat probe exceeding timeout as failed", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		c := cfg(&probe{sleep: time.Second})
+		c.Timeout = time.Millisecond
+		monitor := outage.NewMonitor(c, storage, &countingHandler{})
+
+		monitor.Check(context.Background())
+
+		if _, ok := monitor.Current(); !ok {
+			t.Fatal("expected ongoing outage")
+		}
+	})
+
+	t.Run("should handle storage errors", func(t *testing.T) {
+		handler := &countingHandler{}
+		monitor := outage.NewMonitor(cfg(&probe{down: true}), &failingStorage{}, handler)
+
+		monitor.Check(context.Background())
+
+		if handler.i != 1 {
+			t.Fatalf("expected 1 handled error, actual: %d", handler.i)
+		}
+	})
+}
+
+func TestMonitor_StopBefore(t *testing.T) {
+	t.Run("should push end of ongoing outage before storage is closed", func(t *testing.T) {
+		storage := &closingStorage{Storage: dummy.NewStorage()}
+		monitor := outage.NewMonitor(cfg(&probe{down: true}), storage, &countingHandler{})
+		monitor.Check(context.Background())
+
+		if err := monitor.StopBefore(storage).Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !storage.closed {
+			t.Fatal("expected storage to be closed")
+		}
+		if len(storage.pushedBeforeClose) != 2 || storage.pushedBeforeClose[1].Ongoing() {
+			t.Fatalf("expected finished outage pushed before close, actual: %v", storage.pushedBeforeClose)
+		}
+		if _, ok := monitor.Current(); ok {
+			t.Fatal("expected no ongoing outage")
+		}
+	})
+
+	t.Run("should not push without ongoing outage", func(t *testing.T) {
+		storage := &closingStorage{Storage: dummy.NewStorage()}
+		monitor := outage.NewMonitor(cfg(&probe{}), storage, &countingHandler{})
+		monitor.Check(context.Background())
+
+		if err := monitor.StopBefore(storage).Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(storage.GetOutages()) != 0 {
+			t.Fatalf("expected no outages, actual: %v", storage.GetOutages())
+		}
+	})
+}
+
+func cfg(probes ...outage.Probe) outage.Cfg {
+	return outage.Cfg{Probes: probes, Timeout: time.Second}
+}
+
+type probe struct {
+	down  bool
+	sleep time.Duration
+}
+
+func (p *probe) Probe(ctx context.Context) error {
+	select {
+	case <-time.After(p.sleep):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if p.down {
+		return errors.New("probe failed")
+	}
+	return nil
+}
+
+func (p *probe) String() string {
+	return "test"
+}
+
+type countingHandler struct {
+	i int
+}
+
+func (h *countingHandler) Handle(error) {
+	h.i++
+}
+
+type failingStorage struct{}
+
+func (f *failingStorage) PushOutage(context.Context, core.Outage) error {
+	return errors.New("storage failed")
+}
+
+// closingStorage records outages pushed before it was closed
+type closingStorage struct {
+	*dummy.Storage
+	closed            bool
+	pushedBeforeClose []core.Outage
+}
+
+func (s *closingStorage) Close() error {
+	s.closed = true
+	s.pushedBeforeClose = s.GetOutages()
+	return s.Storage.Close()
+}
@@ -0,0 +1,80 @@
+package outage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Probe checks reachability of a single target. Returns nil when the target is reachable
+type Probe interface {
+	Probe(ctx context.Context) error
+	fmt.Stringer
+}
+
+// TCP probe succeeds when connection to the address can be established
+type TCP struct {
+	Address string
+}
+
+func (p *TCP) Probe(ctx context.Context) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (p *TCP) String() string {
+	return fmt.Sprintf("tcp://%s", p.Address)
+}
+
+// HTTP probe succeeds when the server responds with any status other than 5xx
+type HTTP struct {
+	Url    string
+	Client *http.Client
+}
+
+func (p *HTTP) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Url, nil)
+	if err != nil {
+		return err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *HTTP) String() string {
+	return p.Url
+}
+
+// DNS probe succeeds when the host can be resolved
+type DNS struct {
+	Host     string
+	Resolver *net.Resolver
+}
+
+func (p *DNS) Probe(ctx context.Context) error {
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, err := resolver.LookupHost(ctx, p.Host)
+	return err
+}
+
+func (p *DNS) String() string {
+	return fmt.Sprintf("dns://%s", p.Host)
+}
@@ -0,0 +1,63 @@
+package outage_test
+
+import (
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/outage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTCP_Probe(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+
+	p := &outage.TCP{Address: address}
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("expected listening address to be reachable, actual: %v", err)
+	}
+
+	_ = l.Close()
+	if err := p.Probe(context.Background()); err == nil {
+		t.Fatal("expected closed address to be unreachable")
+	}
+}
+
+func TestHTTP_Probe(t *testing.T) {
+	tests := map[string]struct {
+		status  int
+		wantErr bool
+	}{
+		"should succeed when server responds with 204": {status: http.StatusNoContent},
+		"should succeed when server responds with 404": {status: http.StatusNotFound},
+		"should fail when server responds with 503":    {status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := (&outage.HTTP{Url: server.URL}).Probe(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDNS_Probe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := (&outage.DNS{Host: "localhost"}).Probe(ctx); err != nil {
+		t.Fatalf("expected localhost to be resolvable, actual: %v", err)
+	}
+}
@@ -2,11 +2,21 @@ package resilience
 
 import (
 	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
-func Retry(ctx context.Context, cfg RetryCfg, f func(context.Context) error) error {
-	err := f(ctx)
+const instrumentation = "github.com/paluszkiewiczB/speedtest/internal/resilience"
+
+// Retry calls f until it succeeds or cfg.MaxAttempts is reached. It is traced with the span of every attempt
+func Retry(ctx context.Context, cfg RetryCfg, f func(context.Context) error) (err error) {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, "retry", trace.WithAttributes(attribute.Int("retry.max_attempts", cfg.MaxAttempts())))
+	defer func() { tracing.End(span, err) }()
+
+	err = attempt(ctx, 1, f)
 	if err == nil {
 		return nil
 	}
@@ -19,7 +29,7 @@ func Retry(ctx context.Context, cfg RetryCfg, f func(context.Context) error) err
 	for i := 1; i < cfg.MaxAttempts(); i++ {
 		select {
 		case <-timer.C:
-			err = f(ctx)
+			err = attempt(ctx, i+1, f)
 			if err == nil {
 				return nil
 			}
@@ -32,6 +42,13 @@ func Retry(ctx context.Context, cfg RetryCfg, f func(context.Context) error) err
 	return err
 }
 
+func attempt(ctx context.Context, n int, f func(context.Context) error) error {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, "retry.attempt", trace.WithAttributes(attribute.Int("retry.attempt", n)))
+	err := f(ctx)
+	tracing.End(span, err)
+	return err
+}
+
 type RetryCfg interface {
 	MaxAttempts() int
 	Interval() time.Duration
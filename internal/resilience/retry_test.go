@@ -4,6 +4,11 @@ import (
 	"context"
 	"errors"
 	"github.com/paluszkiewiczB/speedtest/internal/resilience"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"testing"
 	"time"
 )
@@ -74,6 +79,56 @@ func TestRetry(t *testing.T) {
 	})
 }
 
+func TestRetry_Traced(t *testing.T) {
+	spans := recordSpans(t)
+
+	task := failingTask{timeToFail: 2}
+	if err := resilience.Retry(context.Background(), cfg{times: 3}, task.tryToFail); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 4 {
+		t.Fatalf("expected span of retry and 3 attempts, actual: %d spans", len(ended))
+	}
+	retry := ended[3]
+	if retry.Name() != "retry" || retry.Status().Code == codes.Error {
+		t.Fatalf("expected successful retry span, actual: %s, status: %v", retry.Name(), retry.Status())
+	}
+	for i, attempt := range ended[:3] {
+		if attempt.Name() != "retry.attempt" {
+			t.Fatalf("expected attempt span, actual: %s", attempt.Name())
+		}
+		if attempt.Parent().SpanID() != retry.SpanContext().SpanID() {
+			t.Fatalf("attempt %d is not a child of the retry span", i+1)
+		}
+		if !hasAttribute(attempt.Attributes(), attribute.Int("retry.attempt", i+1)) {
+			t.Fatalf("expected attempt number %d, actual attributes: %v", i+1, attempt.Attributes())
+		}
+		if failed := attempt.Status().Code == codes.Error; failed != (i < 2) {
+			t.Fatalf("attempt %d failed: %t", i+1, failed)
+		}
+	}
+}
+
+// recordSpans replaces global tracer provider until the end of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func hasAttribute(attributes []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attributes {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
 type cfg struct {
 	times int
 	wait  time.Duration
@@ -2,14 +2,21 @@ package resilience
 
 import (
 	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
-func Timeout(ctx context.Context, max time.Duration, f func(context.Context) error) error {
+func Timeout(ctx context.Context, max time.Duration, f func(context.Context) error) (err error) {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, "timeout", trace.WithAttributes(attribute.String("timeout.max", max.String())))
+	defer func() { tracing.End(span, err) }()
+
 	timeout, cancel := context.WithTimeout(ctx, max)
 	defer cancel()
 
-	errC := make(chan error)
+	errC := make(chan error, 1)
 
 	go func() {
 		errC <- f(timeout)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/paluszkiewiczB/speedtest/internal/resilience"
+	"go.opentelemetry.io/otel/codes"
 	"testing"
 	"time"
 )
@@ -86,6 +87,26 @@ func TestTimeout(t *testing.T) {
 	})
 }
 
+func TestTimeout_Traced(t *testing.T) {
+	spans := recordSpans(t)
+
+	err := resilience.Timeout(context.Background(), time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, actual: %v", err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != "timeout" {
+		t.Fatalf("expected timeout span, actual: %v", ended)
+	}
+	if ended[0].Status().Code != codes.Error || len(ended[0].Events()) != 1 {
+		t.Fatalf("expected exceeded timeout recorded, actual status: %v, events: %v", ended[0].Status(), ended[0].Events())
+	}
+}
+
 func sleepMs(ms time.Duration, fail bool) func(context.Context) error {
 	return func(ctx context.Context) error {
 		time.Sleep(ms * time.Millisecond)
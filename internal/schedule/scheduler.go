@@ -4,20 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-func NewScheduler() *Scheduler {
+func NewScheduler(opts ...Option) *Scheduler {
 	c := make(map[string]*scheduledTask)
 	mu := &sync.Mutex{}
-	return &Scheduler{cancels: c, mu: mu}
+	s := &Scheduler{cancels: c, mu: mu}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = logging.For(s.logger, "schedule")
+	return s
+}
+
+// Option configures Scheduler created with NewScheduler
+type Option func(s *Scheduler)
+
+// WithLogger sets logger of the Scheduler, logging.Default is used otherwise
+func WithLogger(logger *logging.Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = logger
+	}
 }
 
 type Scheduler struct {
 	cancels map[string]*scheduledTask
 	mu      *sync.Mutex
+	logger  *logging.Logger
 }
 
 func (s *Scheduler) Schedule(ctx context.Context, key string, d time.Duration, task func()) error {
@@ -27,32 +45,34 @@ func (s *Scheduler) Schedule(ctx context.Context, key string, d time.Duration, t
 
 	taskCtx, cancel := context.WithCancel(ctx)
 	ticker := time.NewTicker(d)
-	scheduled := &scheduledTask{cancel: cancel, ticker: ticker}
+	scheduled := &scheduledTask{cancel: cancel, ticker: ticker, task: task, interval: d}
 	err := s.putCancel(key, scheduled)
 	if err != nil {
 		ticker.Stop()
 		return err
 	}
 	go func() {
-		log.Printf("starting task: %s", key)
-		task()
+		s.logger.Debug("starting task", "task", key)
+		scheduled.run()
 
 		for {
 			select {
 			case <-taskCtx.Done():
-				log.Printf("context for task: %s was cancelled", key)
+				s.logger.Debug("context of task was cancelled", "task", key)
 				err := s.Cancel(key)
 				if err != nil {
-					log.Printf("error when removing task")
+					s.logger.Error("could not remove task", "task", key, "err", err)
 				}
 				return
 			case <-ticker.C:
-				log.Printf("starting task: %s", key)
-				task()
+				s.logger.Debug("starting task", "task", key)
+				if !scheduled.run() {
+					s.logger.Debug("task is still running, tick skipped", "task", key)
+				}
 			}
 		}
 	}()
-	log.Printf("scheduled task %s", key)
+	s.logger.Info("scheduled task", "task", key, "interval", d)
 	return nil
 }
 
@@ -67,7 +87,7 @@ func (s *Scheduler) Cancel(key string) error {
 		c.cancel()
 		c.ticker.Stop()
 	}
-	log.Printf("task cancelled: %s", key)
+	s.logger.Info("task cancelled", "task", key)
 	return nil
 }
 
@@ -84,6 +104,67 @@ func (s *Scheduler) Close() error {
 	return nil
 }
 
+// Tasks returns keys of scheduled tasks in no particular order. It returns nil when the Scheduler is closed
+func (s *Scheduler) Tasks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancels == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(s.cancels))
+	for key := range s.cancels {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// TaskState describes scheduled task
+type TaskState struct {
+	Key      string
+	Interval time.Duration
+	Running  bool
+	// LastRun is the start of the last run, zero when the task has not run yet
+	LastRun time.Time
+}
+
+// State returns states of scheduled tasks sorted by their keys. It returns nil when the Scheduler is closed
+func (s *Scheduler) State() []TaskState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancels == nil {
+		return nil
+	}
+	states := make([]TaskState, 0, len(s.cancels))
+	for key, t := range s.cancels {
+		states = append(states, t.state(key))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Key < states[j].Key })
+	return states
+}
+
+// ErrRunning is returned by Run when the task is already running
+var ErrRunning = errors.New("task is already running")
+
+// Run starts scheduled task immediately, without waiting for its interval. The task runs in its own goroutine,
+// unless it is already running
+func (s *Scheduler) Run(key string) error {
+	s.mu.Lock()
+	t, ok := s.cancels[key]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("task with key: %s is not scheduled", key)
+	}
+	if !t.start() {
+		return ErrRunning
+	}
+	s.logger.Info("running task on demand", "task", key)
+	go func() {
+		defer t.finish()
+		t.task()
+	}()
+	return nil
+}
+
 func (s *Scheduler) putCancel(key string, task *scheduledTask) error {
 	s.mu.Lock()
 	_, exists := s.cancels[key]
@@ -97,6 +178,42 @@ func (s *Scheduler) putCancel(key string, task *scheduledTask) error {
 }
 
 type scheduledTask struct {
-	cancel func()
-	ticker *time.Ticker
+	cancel   func()
+	ticker   *time.Ticker
+	task     func()
+	interval time.Duration
+	// running is 1 while the task runs, so ticks and runs on demand do not overlap
+	running int32
+	// lastRun is unix time of the start of the last run in nanoseconds
+	lastRun int64
+}
+
+// run runs the task, unless it is already running. It returns false when the task was not run
+func (t *scheduledTask) run() bool {
+	if !t.start() {
+		return false
+	}
+	defer t.finish()
+	t.task()
+	return true
+}
+
+func (t *scheduledTask) start() bool {
+	if !atomic.CompareAndSwapInt32(&t.running, 0, 1) {
+		return false
+	}
+	atomic.StoreInt64(&t.lastRun, time.Now().UnixNano())
+	return true
+}
+
+func (t *scheduledTask) finish() {
+	atomic.StoreInt32(&t.running, 0)
+}
+
+func (t *scheduledTask) state(key string) TaskState {
+	state := TaskState{Key: key, Interval: t.interval, Running: atomic.LoadInt32(&t.running) == 1}
+	if last := atomic.LoadInt64(&t.lastRun); last != 0 {
+		state.LastRun = time.Unix(0, last)
+	}
+	return state
 }
@@ -112,3 +112,76 @@ func (t *limitTask) inc() {
 		t.cancel()
 	}
 }
+
+func TestScheduler_Tasks(t *testing.T) {
+	scheduler := schedule.NewScheduler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if tasks := scheduler.Tasks(); len(tasks) != 0 {
+		t.Fatalf("expected no tasks, actual: %v", tasks)
+	}
+	if err := scheduler.Schedule(ctx, "TestScheduler_Tasks", time.Hour, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if tasks := scheduler.Tasks(); len(tasks) != 1 || tasks[0] != "TestScheduler_Tasks" {
+		t.Fatalf("expected scheduled task, actual: %v", tasks)
+	}
+	_ = scheduler.Close()
+	if tasks := scheduler.Tasks(); tasks != nil {
+		t.Fatalf("expected no tasks of closed scheduler, actual: %v", tasks)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	scheduler := schedule.NewScheduler()
+	t.Cleanup(func() {
+		_ = scheduler.Close()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan struct{}, 2)
+	release := make(chan struct{})
+	err := scheduler.Schedule(ctx, "TestScheduler_Run", time.Hour, func() {
+		runs <- struct{}{}
+		<-release
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-runs
+	if err := scheduler.Run("TestScheduler_Run"); err != schedule.ErrRunning {
+		t.Fatalf("expected running task not to be run again, actual error: %v", err)
+	}
+	state := scheduler.State()
+	if len(state) != 1 || !state[0].Running || state[0].Interval != time.Hour || state[0].LastRun.IsZero() {
+		t.Fatalf("expected running task, actual: %+v", state)
+	}
+
+	release <- struct{}{}
+	waitFor(t, func() bool { return !scheduler.State()[0].Running })
+	if err := scheduler.Run("TestScheduler_Run"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+		release <- struct{}{}
+	case <-time.After(time.Second):
+		t.Fatal("task was not run on demand")
+	}
+
+	if err := scheduler.Run("unknown"); err == nil {
+		t.Fatal("expected error of task which is not scheduled")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
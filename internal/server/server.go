@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"net"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout limits waiting for requests in progress, when the server is stopped
+const shutdownTimeout = 5 * time.Second
+
+type Cfg struct {
+	Port int
+	// TLS serves HTTPS when not nil. It must contain the certificate of the server
+	TLS *tls.Config
+	// BasicAuth protects handlers, except the public ones, when not nil
+	BasicAuth *BasicAuth
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+type BasicAuth struct {
+	Username, Password string
+}
+
+// New creates server with the mux shared by all subsystems, e.g. metrics, health checks and the UI. Handlers are
+// mounted before the server is run
+func New(cfg Cfg) *Server {
+	return &Server{cfg: cfg, mux: http.NewServeMux(), logger: logging.For(cfg.Logger, "server")}
+}
+
+type Server struct {
+	cfg    Cfg
+	mux    *http.ServeMux
+	logger *logging.Logger
+}
+
+// Handle mounts handler on the pattern of http.ServeMux. Handler is protected with basic auth, when it is configured
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	if s.cfg.BasicAuth != nil {
+		handler = basicAuth(*s.cfg.BasicAuth, handler)
+	}
+	s.mux.Handle(pattern, handler)
+}
+
+// HandlePublic mounts handler, which is never protected with basic auth, e.g. probes of the orchestrator
+func (s *Server) HandlePublic(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run serves requests until ctx is cancelled. Then it waits up to 5 seconds for requests in progress
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("could not listen on port: %d: %w", s.cfg.Port, err)
+	}
+	if s.cfg.TLS != nil {
+		listener = tls.NewListener(listener, s.cfg.TLS)
+	}
+
+	server := &http.Server{Handler: s.mux}
+	stopped := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		stopped <- server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("serving http", "port", s.cfg.Port, "tls", s.cfg.TLS != nil)
+	err = server.Serve(listener)
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return <-stopped
+}
+
+func basicAuth(auth BasicAuth, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || !equal(username, auth.Username) || !equal(password, auth.Password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="speedtest", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,195 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/server"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServer_Handle(t *testing.T) {
+	tests := map[string]struct {
+		auth       *server.BasicAuth
+		path       string
+		user, pass string
+		wantStatus int
+	}{
+		"should serve handler without basic auth": {
+			path:       "/metrics",
+			wantStatus: http.StatusOK,
+		},
+		"should reject request without credentials": {
+			auth:       &server.BasicAuth{Username: "admin", Password: "secret"},
+			path:       "/metrics",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"should reject request with wrong password": {
+			auth:       &server.BasicAuth{Username: "admin", Password: "secret"},
+			path:       "/metrics",
+			user:       "admin",
+			pass:       "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"should serve request with credentials": {
+			auth:       &server.BasicAuth{Username: "admin", Password: "secret"},
+			path:       "/metrics",
+			user:       "admin",
+			pass:       "secret",
+			wantStatus: http.StatusOK,
+		},
+		"should serve public handler without credentials": {
+			auth:       &server.BasicAuth{Username: "admin", Password: "secret"},
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+		},
+		"should not serve unknown path": {
+			path:       "/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := server.New(server.Cfg{BasicAuth: tt.auth})
+			s.Handle("/metrics", ok())
+			s.HandlePublic("/healthz", ok())
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, actual: %d", tt.wantStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("expected challenge of basic auth")
+			}
+		})
+	}
+}
+
+func TestServer_Run(t *testing.T) {
+	t.Run("should serve until context is cancelled", func(t *testing.T) {
+		port := freePort(t)
+		s := server.New(server.Cfg{Port: port})
+		s.Handle("/metrics", ok())
+		stop := run(t, s)
+
+		url := fmt.Sprintf("http://localhost:%d/metrics", port)
+		if status := get(t, http.DefaultClient, url); status != http.StatusOK {
+			t.Fatalf("expected status 200, actual: %d", status)
+		}
+		if err := stop(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := http.Get(url); err == nil {
+			t.Fatal("expected error of stopped server")
+		}
+	})
+
+	t.Run("should serve HTTPS", func(t *testing.T) {
+		cert, pool := selfSigned(t)
+		port := freePort(t)
+		s := server.New(server.Cfg{Port: port, TLS: &tls.Config{Certificates: []tls.Certificate{cert}}})
+		s.Handle("/metrics", ok())
+		stop := run(t, s)
+		defer func() { _ = stop() }()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		if status := get(t, client, fmt.Sprintf("https://127.0.0.1:%d/metrics", port)); status != http.StatusOK {
+			t.Fatalf("expected status 200, actual: %d", status)
+		}
+	})
+
+	t.Run("should fail when port is taken", func(t *testing.T) {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		s := server.New(server.Cfg{Port: l.Addr().(*net.TCPAddr).Port})
+		if err := s.Run(context.Background()); err == nil {
+			t.Fatal("expected error of taken port")
+		}
+	})
+}
+
+func ok() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+}
+
+// run starts the server and waits until it accepts connections. Returned function stops it
+func run(t *testing.T, s *server.Server) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- s.Run(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	return func() error {
+		cancel()
+		return <-errC
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) int {
+	response, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	return response.StatusCode
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "speedtest"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"os"
+)
+
+// DefaultServiceName is the name of the service in exported spans, when Cfg.ServiceName is empty
+const DefaultServiceName = "speedtest"
+
+// Exporter sends finished spans
+type Exporter string
+
+const (
+	Stdout   Exporter = "stdout"
+	OTLPHTTP Exporter = "otlp-http"
+	OTLPGRPC Exporter = "otlp-grpc"
+)
+
+type Cfg struct {
+	Exporter Exporter
+	// Endpoint is host and port of OTLP collector. Default endpoint of the exporter is used when empty
+	Endpoint string
+	// Insecure disables TLS of OTLP exporters
+	Insecure bool
+	// Headers are sent with every OTLP request, e.g. to authenticate
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio is the fraction of traces which are sampled. All traces are sampled when it is not in (0, 1)
+	SampleRatio float64
+	// Output of Stdout exporter is os.Stdout when nil
+	Output io.Writer
+}
+
+// Setup registers global tracer provider exporting spans with the exporter and W3C trace context propagator.
+// Instrumented packages look up their tracer on every span, so they follow the provider. Returned function flushes
+// pending spans and stops the provider
+func Setup(ctx context.Context, cfg Cfg) (func(context.Context) error, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(name)))
+	if err != nil {
+		return nil, fmt.Errorf("could not create tracing resource: %w", err)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Cfg) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case Stdout:
+		output := cfg.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(output))
+	case OTLPHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptrace.New(ctx, otlptracehttp.NewClient(opts...))
+	case OTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptrace.New(ctx, otlptracegrpc.NewClient(opts...))
+	}
+	return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+}
+
+// End records err, if it is not nil, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,40 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/paluszkiewiczB/speedtest/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	out := &bytes.Buffer{}
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Cfg{Exporter: tracing.Stdout, ServiceName: "home", Output: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "speedtest.run")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	exported := out.String()
+	if !strings.Contains(exported, `"Name":"speedtest.run"`) {
+		t.Fatalf("expected exported span, actual: %s", exported)
+	}
+	if !strings.Contains(exported, `"Value":"home"`) {
+		t.Fatalf("expected service name in the resource, actual: %s", exported)
+	}
+}
+
+func TestSetup_UnsupportedExporter(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Cfg{Exporter: "zipkin"}); err == nil {
+		t.Fatal("expected error of unsupported exporter")
+	}
+}
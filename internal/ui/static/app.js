@@ -0,0 +1,156 @@
+"use strict";
+
+const refreshInterval = 15000;
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+async function getJSON(url) {
+  const response = await fetch(url, {cache: "no-store"});
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "never";
+}
+
+function fixed(value) {
+  return value.toFixed(2);
+}
+
+async function refreshStatus() {
+  const status = await getJSON("api/status");
+  if (status.last) {
+    const speed = status.last.speed;
+    $("last-download").textContent = fixed(speed.download);
+    $("last-upload").textContent = fixed(speed.upload);
+    $("last-ping").textContent = fixed(speed.ping);
+    $("last-time").textContent = formatTime(speed.time);
+    $("last-job").textContent = "job: " + status.last.job;
+  }
+
+  const rows = status.tasks.map(function (task) {
+    const row = document.createElement("tr");
+    [task.key, task.interval, task.running ? "running" : "idle", formatTime(task.last_run)].forEach(function (text) {
+      const cell = document.createElement("td");
+      cell.textContent = text;
+      row.appendChild(cell);
+    });
+    const button = document.createElement("button");
+    button.textContent = "Run now";
+    button.disabled = task.running;
+    button.addEventListener("click", function () {
+      run(task.key, button);
+    });
+    const cell = document.createElement("td");
+    cell.appendChild(button);
+    row.appendChild(cell);
+    return row;
+  });
+  $("tasks").tBodies[0].replaceChildren(...rows);
+  $("history").hidden = !status.charts;
+}
+
+async function run(key, button) {
+  button.disabled = true;
+  const response = await fetch("api/run", {
+    method: "POST",
+    headers: {"Content-Type": "application/json"},
+    body: JSON.stringify({task: key}),
+  });
+  const body = await response.json();
+  $("run-message").textContent = response.ok ? "Started " + key : "Could not start " + key + ": " + body.error;
+  refreshStatus().catch(showError);
+}
+
+const svgNS = "http://www.w3.org/2000/svg";
+
+function element(name, attributes, text) {
+  const e = document.createElementNS(svgNS, name);
+  Object.keys(attributes).forEach(function (key) {
+    e.setAttribute(key, attributes[key]);
+  });
+  if (text !== undefined) {
+    e.textContent = text;
+  }
+  return e;
+}
+
+// drawChart draws lines of the series with value axis starting at zero and time axis of the points
+function drawChart(svg, points, series) {
+  const width = svg.clientWidth || 800;
+  const height = svg.clientHeight || 260;
+  const pad = {left: 48, right: 12, top: 10, bottom: 24};
+  svg.setAttribute("viewBox", "0 0 " + width + " " + height);
+  svg.replaceChildren();
+  if (points.length === 0) {
+    svg.appendChild(element("text", {x: width / 2, y: height / 2, "text-anchor": "middle"}, "no results in the range"));
+    return;
+  }
+
+  const times = points.map(function (p) { return new Date(p.time).getTime(); });
+  const minTime = Math.min(...times);
+  const maxTime = Math.max(...times);
+  let maxValue = 0;
+  series.forEach(function (s) {
+    points.forEach(function (p) { maxValue = Math.max(maxValue, p[s]); });
+  });
+  maxValue = maxValue > 0 ? maxValue * 1.1 : 1;
+
+  const x = function (t) {
+    return maxTime === minTime ? pad.left : pad.left + (t - minTime) / (maxTime - minTime) * (width - pad.left - pad.right);
+  };
+  const y = function (v) {
+    return height - pad.bottom - v / maxValue * (height - pad.top - pad.bottom);
+  };
+
+  for (let i = 0; i <= 4; i++) {
+    const value = maxValue * i / 4;
+    svg.appendChild(element("line", {class: "axis", x1: pad.left, x2: width - pad.right, y1: y(value), y2: y(value)}));
+    svg.appendChild(element("text", {x: pad.left - 6, y: y(value) + 4, "text-anchor": "end"}, value.toFixed(0)));
+  }
+  [minTime, maxTime].forEach(function (t, i) {
+    const anchor = i === 0 ? "start" : "end";
+    svg.appendChild(element("text", {x: x(t), y: height - 6, "text-anchor": anchor}, new Date(t).toLocaleString()));
+  });
+
+  series.forEach(function (s) {
+    const coordinates = points.map(function (p, i) { return x(times[i]) + "," + y(p[s]); }).join(" ");
+    svg.appendChild(element("polyline", {class: s, points: coordinates}));
+  });
+}
+
+async function refreshHistory() {
+  if ($("history").hidden) {
+    return;
+  }
+  const points = await getJSON("api/speeds?range=" + encodeURIComponent($("range").value));
+  $("history-message").textContent = points.length + " points";
+  drawChart($("chart-speed"), points, ["download", "upload"]);
+  drawChart($("chart-ping"), points, ["ping"]);
+}
+
+function showError(err) {
+  $("updated").textContent = "update failed: " + err.message;
+}
+
+async function refresh() {
+  try {
+    await refreshStatus();
+    await refreshHistory();
+    $("updated").textContent = "updated " + new Date().toLocaleTimeString();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+$("range").addEventListener("change", function () {
+  refreshHistory().catch(showError);
+});
+refresh();
+setInterval(refresh, refreshInterval);
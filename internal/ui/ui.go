@@ -0,0 +1,257 @@
+package ui
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/schedule"
+	"io/fs"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//go:embed static
+var static embed.FS
+
+// maxPoints limits points of the chart. Longer history is averaged in buckets of equal duration
+const maxPoints = 720
+
+// Scheduler shows and runs scheduled tasks, e.g. schedule.Scheduler
+type Scheduler interface {
+	State() []schedule.TaskState
+	Run(key string) error
+}
+
+type Cfg struct {
+	// Storage provides history of the charts. Charts are not shown when nil
+	Storage core.Queryable
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// New creates dashboard of the speed test. UI must be subscribed to the bus to show the last result
+func New(cfg Cfg, scheduler Scheduler) *UI {
+	return &UI{storage: cfg.Storage, scheduler: scheduler, logger: logging.For(cfg.Logger, "ui")}
+}
+
+type UI struct {
+	storage   core.Queryable
+	scheduler Scheduler
+	logger    *logging.Logger
+
+	mu   sync.Mutex
+	last *result
+}
+
+// result is the last speed measured by the job
+type result struct {
+	Job   string `json:"job"`
+	Point point  `json:"speed"`
+}
+
+type point struct {
+	Time     time.Time         `json:"time"`
+	Download float64           `json:"download"`
+	Upload   float64           `json:"upload"`
+	Ping     float64           `json:"ping"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+func toPoint(s core.Speed) point {
+	return point{Time: s.Timestamp, Download: s.Download, Upload: s.Upload, Ping: ms(s.Ping), Tags: s.Tags}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (u *UI) Notify(e core.Event) {
+	if e, ok := e.(core.ResultProduced); ok {
+		u.mu.Lock()
+		u.last = &result{Job: e.Job, Point: toPoint(e.Speed)}
+		u.mu.Unlock()
+	}
+}
+
+func init() {
+	// some systems map .js to text/plain, which browsers refuse to execute
+	_ = mime.AddExtensionType(".js", "text/javascript")
+}
+
+// Handler serves the dashboard at / and its API at /api/. It is meant to be mounted at the root of the server
+func (u *UI) Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/api/speeds", u.speeds)
+	mux.HandleFunc("/api/status", u.status)
+	mux.HandleFunc("/api/run", u.run)
+	return mux
+}
+
+// ranges are periods of the history selectable in the dashboard
+var ranges = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+func (u *UI) speeds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if u.storage == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("storage is not queryable"))
+		return
+	}
+	name := r.URL.Query().Get("range")
+	if name == "" {
+		name = "24h"
+	}
+	period, ok := ranges[name]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported range: %s", name))
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-period)
+	speeds, err := u.storage.Query(r.Context(), from, to)
+	if err != nil {
+		u.logger.Warn("could not query speeds", "err", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, downsample(speeds, from, to, maxPoints))
+}
+
+// downsample averages speeds in n buckets of equal duration between from and to. Speeds are returned as they are,
+// when there are at most n of them. Charts show a single series, so speeds of all jobs are averaged together and
+// tags of the averaged points are dropped
+func downsample(speeds []core.Speed, from, to time.Time, n int) []point {
+	points := make([]point, 0, len(speeds))
+	if len(speeds) <= n {
+		for _, s := range speeds {
+			points = append(points, toPoint(s))
+		}
+		return points
+	}
+
+	step := to.Sub(from) / time.Duration(n)
+	var sum point
+	count, bucket := 0, -1
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		c := float64(count)
+		points = append(points, point{
+			Time:     from.Add(time.Duration(bucket)*step + step/2),
+			Download: sum.Download / c,
+			Upload:   sum.Upload / c,
+			Ping:     sum.Ping / c,
+		})
+	}
+	for _, s := range speeds {
+		b := int(s.Timestamp.Sub(from) / step)
+		if b != bucket {
+			flush()
+			sum, count, bucket = point{}, 0, b
+		}
+		sum.Download += s.Download
+		sum.Upload += s.Upload
+		sum.Ping += ms(s.Ping)
+		count++
+	}
+	flush()
+	return points
+}
+
+type status struct {
+	Last   *result `json:"last"`
+	Tasks  []task  `json:"tasks"`
+	Charts bool    `json:"charts"`
+}
+
+type task struct {
+	Key      string     `json:"key"`
+	Interval string     `json:"interval"`
+	Running  bool       `json:"running"`
+	LastRun  *time.Time `json:"last_run,omitempty"`
+}
+
+func (u *UI) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	u.mu.Lock()
+	s := status{Last: u.last, Tasks: make([]task, 0), Charts: u.storage != nil}
+	u.mu.Unlock()
+	for _, state := range u.scheduler.State() {
+		t := task{Key: state.Key, Interval: state.Interval.String(), Running: state.Running}
+		if !state.LastRun.IsZero() {
+			lastRun := state.LastRun
+			t.LastRun = &lastRun
+		}
+		s.Tasks = append(s.Tasks, t)
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+type runRequest struct {
+	Task string `json:"task"`
+}
+
+// run starts the task on demand. Request must be JSON, so browsers do not send it from other sites without CORS
+func (u *UI) run(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("request must be application/json"))
+		return
+	}
+	var req runRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := u.scheduler.Run(req.Task)
+	switch {
+	case err == schedule.ErrRunning:
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeJSON(w, http.StatusAccepted, req)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,220 @@
+package ui_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/dummy"
+	"github.com/paluszkiewiczB/speedtest/internal/schedule"
+	"github.com/paluszkiewiczB/speedtest/internal/ui"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUI_Static(t *testing.T) {
+	handler := ui.New(ui.Cfg{}, &scheduler{}).Handler()
+	tests := map[string]string{
+		"/":          "<title>Speed test</title>",
+		"/app.js":    "api/status",
+		"/style.css": ".chart",
+	}
+
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			w := serve(handler, http.MethodGet, path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, actual: %d", w.Code)
+			}
+			if body := w.Body.String(); !strings.Contains(body, want) {
+				t.Fatalf("expected %q in the body, actual: %s", want, body)
+			}
+		})
+	}
+}
+
+func TestUI_Status(t *testing.T) {
+	lastRun := time.Unix(1000, 0)
+	u := ui.New(ui.Cfg{}, &scheduler{states: []schedule.TaskState{
+		{Key: "home", Interval: time.Minute, Running: true, LastRun: lastRun},
+		{Key: "office", Interval: time.Hour},
+	}})
+	u.Notify(core.ResultProduced{Job: "home", Speed: core.Speed{Download: 100, Upload: 20, Ping: 15 * time.Millisecond}})
+
+	w := serve(u.Handler(), http.MethodGet, "/api/status", "")
+	var got struct {
+		Last struct {
+			Job   string
+			Speed struct{ Download, Upload, Ping float64 }
+		}
+		Tasks []struct {
+			Key, Interval string
+			Running       bool
+			LastRun       *time.Time `json:"last_run"`
+		}
+		Charts bool
+	}
+	decode(t, w, &got)
+
+	if got.Last.Job != "home" || got.Last.Speed.Download != 100 || got.Last.Speed.Upload != 20 || got.Last.Speed.Ping != 15 {
+		t.Errorf("unexpected last result: %+v", got.Last)
+	}
+	if len(got.Tasks) != 2 || !got.Tasks[0].Running || got.Tasks[0].Interval != "1m0s" || !got.Tasks[0].LastRun.Equal(lastRun) {
+		t.Errorf("unexpected tasks: %+v", got.Tasks)
+	}
+	if got.Tasks[1].LastRun != nil {
+		t.Errorf("expected no last run of the task which did not run, actual: %v", got.Tasks[1].LastRun)
+	}
+	if got.Charts {
+		t.Error("expected no charts without storage")
+	}
+}
+
+func TestUI_Run(t *testing.T) {
+	tests := map[string]struct {
+		method, contentType, body string
+		wantStatus                int
+		wantRun                   string
+	}{
+		"should run the task": {
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"task": "home"}`,
+			wantStatus:  http.StatusAccepted,
+			wantRun:     "home",
+		},
+		"should not run the task which is already running": {
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"task": "busy"}`,
+			wantStatus:  http.StatusConflict,
+		},
+		"should not run unknown task": {
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"task": "unknown"}`,
+			wantStatus:  http.StatusNotFound,
+		},
+		"should reject form": {
+			method:      http.MethodPost,
+			contentType: "application/x-www-form-urlencoded",
+			body:        "task=home",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		"should reject GET": {
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &scheduler{}
+			w := serve(ui.New(ui.Cfg{}, s).Handler(), tt.method, "/api/run", tt.body, tt.contentType)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, actual: %d, body: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if s.run != tt.wantRun {
+				t.Fatalf("expected run of %q, actual: %q", tt.wantRun, s.run)
+			}
+		})
+	}
+}
+
+func TestUI_Speeds(t *testing.T) {
+	t.Run("should return speeds of the range", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		now := time.Now()
+		_ = storage.Push(context.Background(), core.Speed{Download: 1, Timestamp: now.Add(-2 * time.Hour)})
+		_ = storage.Push(context.Background(), core.Speed{Download: 2, Ping: time.Millisecond, Timestamp: now.Add(-time.Minute)})
+
+		w := serve(ui.New(ui.Cfg{Storage: storage}, &scheduler{}).Handler(), http.MethodGet, "/api/speeds?range=1h", "")
+		var points []struct{ Download, Ping float64 }
+		decode(t, w, &points)
+		if len(points) != 1 || points[0].Download != 2 || points[0].Ping != 1 {
+			t.Fatalf("expected speed of the last hour, actual: %+v", points)
+		}
+	})
+
+	t.Run("should average long history", func(t *testing.T) {
+		storage := dummy.NewStorage()
+		now := time.Now()
+		for i := 0; i < 2000; i++ {
+			_ = storage.Push(context.Background(), core.Speed{Download: 10, Upload: 5, Timestamp: now.Add(-time.Duration(i) * time.Second)})
+		}
+
+		w := serve(ui.New(ui.Cfg{Storage: storage}, &scheduler{}).Handler(), http.MethodGet, "/api/speeds?range=1h", "")
+		var points []struct{ Download, Upload float64 }
+		decode(t, w, &points)
+		if len(points) == 0 || len(points) > 720 {
+			t.Fatalf("expected at most 720 points, actual: %d", len(points))
+		}
+		for _, p := range points {
+			if p.Download != 10 || p.Upload != 5 {
+				t.Fatalf("expected averages of equal speeds, actual: %+v", p)
+			}
+		}
+	})
+
+	t.Run("should reject unsupported range", func(t *testing.T) {
+		w := serve(ui.New(ui.Cfg{Storage: dummy.NewStorage()}, &scheduler{}).Handler(), http.MethodGet, "/api/speeds?range=1y", "")
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, actual: %d", w.Code)
+		}
+	})
+
+	t.Run("should fail without storage", func(t *testing.T) {
+		w := serve(ui.New(ui.Cfg{}, &scheduler{}).Handler(), http.MethodGet, "/api/speeds", "")
+		if w.Code != http.StatusNotImplemented {
+			t.Fatalf("expected status 501, actual: %d", w.Code)
+		}
+	})
+}
+
+func serve(h http.Handler, method, path, body string, contentType ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(contentType) > 0 {
+		r.Header.Set("Content-Type", contentType[0])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, actual: %d", w.Code)
+	}
+	body, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("could not decode: %s: %s", body, err)
+	}
+}
+
+type scheduler struct {
+	states []schedule.TaskState
+	run    string
+}
+
+func (s *scheduler) State() []schedule.TaskState {
+	return s.states
+}
+
+func (s *scheduler) Run(key string) error {
+	switch key {
+	case "home":
+		s.run = key
+		return nil
+	case "busy":
+		return schedule.ErrRunning
+	}
+	return errors.New("task is not scheduled")
+}
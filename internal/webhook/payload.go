@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"time"
+)
+
+// payload is the JSON body sent when there is no template. Type is one of: result, alert, error
+type payload struct {
+	Type string `json:"type"`
+
+	// Download, Upload and PingMs are pointers, so results of failed tests are sent with zeros
+	Download  *float64          `json:"download,omitempty"`
+	Upload    *float64          `json:"upload,omitempty"`
+	PingMs    *float64          `json:"ping_ms,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+
+	Rule        string     `json:"rule,omitempty"`
+	Job         string     `json:"job,omitempty"`
+	State       string     `json:"state,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Value       *float64   `json:"value,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+
+	Error       string `json:"error,omitempty"`
+	Phase       string `json:"phase,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Retryable   *bool  `json:"retryable,omitempty"`
+	NetworkDown *bool  `json:"network_down,omitempty"`
+}
+
+func resultPayload(s core.Speed) payload {
+	ping := float64(s.Ping) / float64(time.Millisecond)
+	return payload{
+		Type:      "result",
+		Download:  &s.Download,
+		Upload:    &s.Upload,
+		PingMs:    &ping,
+		Timestamp: &s.Timestamp,
+		Tags:      s.Tags,
+	}
+}
+
+func alertPayload(a alert.Alert) payload {
+	p := payload{
+		Type:        "alert",
+		Rule:        a.Rule,
+		Job:         a.Job,
+		State:       string(a.State),
+		Description: a.Description,
+		Value:       &a.Value,
+		StartsAt:    &a.StartsAt,
+		Tags:        a.Tags,
+	}
+	if !a.EndsAt.IsZero() {
+		p.EndsAt = &a.EndsAt
+	}
+	return p
+}
+
+func errorPayload(e Error) payload {
+	p := payload{Type: "error", Error: e.Message}
+	if e.Phase != "" {
+		p.Phase = string(e.Phase)
+		p.Kind = string(e.Kind)
+		p.Retryable = &e.Retryable
+		p.NetworkDown = &e.NetworkDown
+	}
+	return p
+}
@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/logging"
+	"github.com/paluszkiewiczB/speedtest/internal/resilience"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultSignatureHeader carries HMAC-SHA256 of the request body, formatted as sha256=<hex>
+const DefaultSignatureHeader = "X-Signature-256"
+
+type Cfg struct {
+	Url string
+	// Method is POST when empty
+	Method string
+	// Headers are added to every request. Content-Type is application/json, unless it is overridden
+	Headers map[string]string
+	// Templates of request bodies. JSON is sent for empty template
+	Templates Templates
+	// Secret signs request bodies with HMAC-SHA256. Requests are not signed when empty
+	Secret string
+	// SignatureHeader is DefaultSignatureHeader when empty
+	SignatureHeader string
+	Retry           RetryCfg
+	// Timeout limits every attempt. Zero means no limit
+	Timeout time.Duration
+	// HandleTimeout limits sending of every error passed to Handle, including retries. One minute when zero
+	HandleTimeout time.Duration
+	Client        *http.Client
+	// Logger is logging.Default when nil
+	Logger *logging.Logger
+}
+
+// Templates are text/template sources rendered with: core.Speed for Result, alert.Alert for Alert and Error for Error.
+// Function json encodes its argument, e.g. {{json .Description}}
+type Templates struct {
+	Result, Alert, Error string
+}
+
+// RetryCfg repeats failed requests. Zero value sends every request once
+type RetryCfg struct {
+	Times int
+	Wait  time.Duration
+}
+
+func (c RetryCfg) MaxAttempts() int {
+	return c.Times
+}
+
+func (c RetryCfg) Interval() time.Duration {
+	return c.Wait
+}
+
+// Error is passed to Error template
+type Error struct {
+	Message string
+	// Phase, Kind, Retryable and NetworkDown are set when error was classified with core.Classify
+	Phase       core.Phase
+	Kind        core.Kind
+	Retryable   bool
+	NetworkDown bool
+}
+
+func New(cfg Cfg) (*Webhook, error) {
+	if cfg.Url == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+	if cfg.HandleTimeout <= 0 {
+		cfg.HandleTimeout = time.Minute
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	w := &Webhook{cfg: cfg, client: client, logger: logging.For(cfg.Logger, "webhook")}
+	var err error
+	if w.resultT, err = parse("result", cfg.Templates.Result); err != nil {
+		return nil, err
+	}
+	if w.alertT, err = parse("alert", cfg.Templates.Alert); err != nil {
+		return nil, err
+	}
+	if w.errorT, err = parse("error", cfg.Templates.Error); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Webhook sends results as core.Storage, alerts as alert.Notifier and errors as core.ErrorHandler
+type Webhook struct {
+	cfg                     Cfg
+	client                  *http.Client
+	resultT, alertT, errorT *template.Template
+	logger                  *logging.Logger
+	// handling counts errors passed to Handle, which are still being sent
+	handling sync.WaitGroup
+}
+
+func (w *Webhook) Push(ctx context.Context, speed core.Speed) error {
+	body, err := w.render(w.resultT, speed, resultPayload(speed))
+	if err != nil {
+		return core.Classify(core.StoragePhase, err)
+	}
+	return core.Classify(core.StoragePhase, w.send(ctx, body))
+}
+
+// Close waits until errors passed to Handle are sent, each of them at most HandleTimeout
+func (w *Webhook) Close() error {
+	w.handling.Wait()
+	return nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, a alert.Alert) error {
+	body, err := w.render(w.alertT, a, alertPayload(a))
+	if err != nil {
+		return err
+	}
+	return w.send(ctx, body)
+}
+
+// Handle sends err to the webhook in the background, so slow webhook does not delay the caller. Errors of
+// the webhook itself can only be logged. Close waits for the sending
+func (w *Webhook) Handle(err error) {
+	e := Error{Message: err.Error()}
+	if c := core.AsError(err); c != nil {
+		e.Phase, e.Kind, e.Retryable, e.NetworkDown = c.Phase, c.Kind, c.Retryable, c.NetworkDown
+	}
+	body, rErr := w.render(w.errorT, e, errorPayload(e))
+	if rErr != nil {
+		w.logger.Error("could not render error for webhook", "handled_err", err, "err", rErr)
+		return
+	}
+	w.handling.Add(1)
+	go func() {
+		defer w.handling.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), w.cfg.HandleTimeout)
+		defer cancel()
+		if sErr := w.send(ctx, body); sErr != nil {
+			w.logger.Error("could not send error to webhook", "handled_err", err, "err", sErr)
+		}
+	}()
+}
+
+// render executes t with data, or encodes payload as JSON when t is nil
+func (w *Webhook) render(t *template.Template, data, payload interface{}) ([]byte, error) {
+	if t == nil {
+		return json.Marshal(payload)
+	}
+	buf := &bytes.Buffer{}
+	err := t.Execute(buf, data)
+	return buf.Bytes(), err
+}
+
+func (w *Webhook) send(ctx context.Context, body []byte) error {
+	return resilience.Retry(ctx, w.cfg.Retry, func(ctx context.Context) error {
+		if w.cfg.Timeout > 0 {
+			return resilience.Timeout(ctx, w.cfg.Timeout, func(ctx context.Context) error {
+				return w.request(ctx, body)
+			})
+		}
+		return w.request(ctx, body)
+	})
+}
+
+func (w *Webhook) request(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, w.cfg.Method, w.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.cfg.Secret != "" {
+		req.Header.Set(w.cfg.SignatureHeader, Sign(w.cfg.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns value of the signature header for body signed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func parse(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
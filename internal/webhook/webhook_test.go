@@ -0,0 +1,252 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/paluszkiewiczB/speedtest/internal/alert"
+	"github.com/paluszkiewiczB/speedtest/internal/core"
+	"github.com/paluszkiewiczB/speedtest/internal/webhook"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var speed = core.Speed{Download: 300, Upload: 50, Ping: 12 * time.Millisecond, Timestamp: time.Unix(100, 0).UTC(), Tags: map[string]string{"job": "home"}}
+
+func TestWebhook_Push(t *testing.T) {
+	server := newServer(t, http.StatusOK)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+
+	err := w.Push(context.Background(), speed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.only(t)
+	if req.method != http.MethodPost {
+		t.Fatalf("expected POST, actual: %s", req.method)
+	}
+	if req.header.Get("Authorization") != "Bearer token" || req.header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected configured headers, actual: %v", req.header)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["type"] != "result" || body["download"] != 300.0 || body["ping_ms"] != 12.0 {
+		t.Fatalf("unexpected body: %s", req.body)
+	}
+}
+
+func TestWebhook_Template(t *testing.T) {
+	server := newServer(t, http.StatusOK)
+	w := newWebhook(t, webhook.Cfg{
+		Url:       server.URL,
+		Method:    http.MethodPut,
+		Templates: webhook.Templates{Alert: `{"text": {{json (printf "%s is %s for %s" .Rule .State .Job)}}}`},
+	})
+
+	err := w.Notify(context.Background(), alert.Alert{Rule: "slow-download", Job: "home", State: alert.Firing})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.only(t)
+	if req.method != http.MethodPut {
+		t.Fatalf("expected PUT, actual: %s", req.method)
+	}
+	if string(req.body) != `{"text": "slow-download is firing for home"}` {
+		t.Fatalf("unexpected body: %s", req.body)
+	}
+}
+
+func TestWebhook_InvalidTemplate(t *testing.T) {
+	_, err := webhook.New(webhook.Cfg{Url: "http://localhost", Templates: webhook.Templates{Result: "{{.Download"}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestWebhook_Signature(t *testing.T) {
+	server := newServer(t, http.StatusOK)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL, Secret: "s3cr3t", SignatureHeader: "X-Hub-Signature-256"})
+
+	err := w.Push(context.Background(), speed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.only(t)
+	signature := req.header.Get("X-Hub-Signature-256")
+	if signature == "" || signature != webhook.Sign("s3cr3t", req.body) {
+		t.Fatalf("expected body signed with secret, actual signature: %s", signature)
+	}
+}
+
+func TestWebhook_Retry(t *testing.T) {
+	server := newServer(t, http.StatusInternalServerError)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL, Retry: webhook.RetryCfg{Times: 3, Wait: time.Millisecond}})
+
+	err := w.Push(context.Background(), speed)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if e := core.AsError(err); e == nil || e.Phase != core.StoragePhase {
+		t.Fatalf("expected storage error, actual: %v", err)
+	}
+	if n := len(server.get()); n != 3 {
+		t.Fatalf("expected 3 attempts, actual: %d", n)
+	}
+}
+
+func TestWebhook_Handle(t *testing.T) {
+	server := newServer(t, http.StatusOK)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL})
+
+	w.Handle(core.Classify(core.DownloadPhase, context.DeadlineExceeded))
+	w.Handle(errors.New("plain"))
+
+	requests := server.wait(t, 2)
+	var classified, plain map[string]interface{}
+	for _, r := range requests {
+		var body map[string]interface{}
+		_ = json.Unmarshal(r.body, &body)
+		if body["error"] == "plain" {
+			plain = body
+		} else {
+			classified = body
+		}
+	}
+	if classified["phase"] != string(core.DownloadPhase) || classified["kind"] != string(core.TimeoutKind) || classified["retryable"] != true {
+		t.Fatalf("expected classification in body, actual: %v", classified)
+	}
+	if plain["error"] != "plain" || plain["phase"] != nil {
+		t.Fatalf("expected only message in body, actual: %v", plain)
+	}
+}
+
+func TestWebhook_HandleDoesNotWaitForWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	w := newWebhook(t, webhook.Cfg{Url: server.URL})
+
+	handled := make(chan struct{})
+	go func() {
+		w.Handle(errors.New("storage is down"))
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("expected Handle to return before webhook responds")
+	}
+}
+
+func TestWebhook_CloseWaitsForHandle(t *testing.T) {
+	var sent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&sent, 1)
+	}))
+	t.Cleanup(server.Close)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL})
+
+	w.Handle(errors.New("storage is down"))
+	w.Handle(errors.New("test failed"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&sent); n != 2 {
+		t.Fatalf("expected 2 errors sent before Close returned, actual: %d", n)
+	}
+}
+
+func TestWebhook_PushSendsZeros(t *testing.T) {
+	server := newServer(t, http.StatusOK)
+	w := newWebhook(t, webhook.Cfg{Url: server.URL})
+
+	err := w.Push(context.Background(), core.Speed{Timestamp: time.Unix(100, 0).UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(server.only(t).body, &body); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"download", "upload", "ping_ms"} {
+		if v, ok := body[field]; !ok || v != 0.0 {
+			t.Fatalf("expected %s: 0, actual body: %v", field, body)
+		}
+	}
+}
+
+func newWebhook(t *testing.T, cfg webhook.Cfg) *webhook.Webhook {
+	w, err := webhook.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+type request struct {
+	method string
+	header http.Header
+	body   []byte
+}
+
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+}
+
+// newServer records all requests and responds with status
+func newServer(t *testing.T, status int) *server {
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, request{method: r.Method, header: r.Header, body: body})
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) get() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// wait returns requests when there are n of them, or fails after a second
+func (s *server) wait(t *testing.T, n int) []request {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if requests := s.get(); len(requests) == n {
+			return requests
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d requests, actual: %d", n, len(s.get()))
+	return nil
+}
+
+func (s *server) only(t *testing.T) request {
+	requests := s.get()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, actual: %d", len(requests))
+	}
+	return requests[0]
+}